@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+)
+
+const (
+	DEFAULT_PER_PAGE = 30
+	MAX_PER_PAGE     = 500
+)
+
+// registerItemsAPI adds the JSON API for managing the team items and the
+// personal items of the user of the device:
+//
+//	GET    /api/v1/items?page=1&perPage=30&tag=work
+//	GET    /api/v1/items/:id
+//	POST   /api/v1/items
+//	PATCH  /api/v1/items/:id
+//	DELETE /api/v1/items/:id
+func registerItemsAPI(router *echo.Echo, pb *pocketbase.PocketBase, authMiddleware *AuthMiddleware) {
+	group := router.Group("/api/v1/items", authMiddleware.Process)
+
+	group.GET("", func(c echo.Context) error {
+		page, err := queryInt(c, "page", 1)
+		if err != nil {
+			return err
+		}
+		perPage, err := queryInt(c, "perPage", DEFAULT_PER_PAGE)
+		if err != nil {
+			return err
+		}
+		perPage = min(perPage, MAX_PER_PAGE)
+		result, err := listItems(pb, c.QueryParam("tag"), page, perPage, getUserID(c))
+		if err != nil {
+			return apis.NewBadRequestError("Failed to list items.", err)
+		}
+		return c.JSON(http.StatusOK, result)
+	})
+
+	group.GET("/:id", func(c echo.Context) error {
+		item, err := getItemByID(pb, c.PathParam("id"), getUserID(c))
+		if err != nil {
+			return itemsAPIError(err)
+		}
+		return c.JSON(http.StatusOK, item)
+	})
+
+	group.POST("", func(c echo.Context) error {
+		var item Item
+		if err := c.Bind(&item); err != nil {
+			return apis.NewBadRequestError("Failed to read the request body.", err)
+		}
+		if item.Owner != "" && item.Owner != getUserID(c) {
+			return apis.NewBadRequestError("Failed to validate the item.", validation.Errors{
+				"owner": validation.NewError("validation_invalid_owner", "must be empty or the user of the device"),
+			})
+		}
+		item, err := createItem(pb, item)
+		if err != nil {
+			return itemsAPIError(err)
+		}
+		if item, err = getItemByID(pb, item.ID, getUserID(c)); err != nil {
+			return itemsAPIError(err)
+		}
+		return c.JSON(http.StatusCreated, item)
+	})
+
+	group.PATCH("/:id", func(c echo.Context) error {
+		item, err := getItemByID(pb, c.PathParam("id"), getUserID(c))
+		if err != nil {
+			return itemsAPIError(err)
+		}
+		owner := item.Owner
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return apis.NewBadRequestError("Failed to read the request body.", err)
+		}
+		// Variants are replaced as a whole, decoding would merge them into
+		// the loaded ones otherwise. Bind reports the malformed bodies.
+		var patch struct {
+			Variants json.RawMessage `json:"variants"`
+		}
+		if json.Unmarshal(body, &patch) == nil && patch.Variants != nil {
+			item.Variants = nil
+		}
+		// Only the fields present in the body are changed
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+		if err := c.Bind(&item); err != nil {
+			return apis.NewBadRequestError("Failed to read the request body.", err)
+		}
+		item.ID = c.PathParam("id")
+		// Personal items stay personal and team items stay shared
+		item.Owner = owner
+		item, err = updateItem(pb, item)
+		if err != nil {
+			return itemsAPIError(err)
+		}
+		return c.JSON(http.StatusOK, item)
+	})
+
+	group.DELETE("/:id", func(c echo.Context) error {
+		if err := deleteItem(pb, c.PathParam("id"), getUserID(c)); err != nil {
+			return itemsAPIError(err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+}
+
+func queryInt(c echo.Context, name string, fallback int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, apis.NewBadRequestError("", validation.Errors{
+			name: validation.NewError("validation_invalid_number", "Must be a positive integer."),
+		})
+	}
+	return n, nil
+}
+
+// itemsAPIError converts the errors to the PocketBase JSON errors, the
+// validation errors are returned field by field.
+func itemsAPIError(err error) error {
+	var validationErrors validation.Errors
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return apis.NewNotFoundError("", nil)
+	case errors.Is(err, errForeignChildren):
+		return apis.NewBadRequestError(err.Error(), nil)
+	case errors.As(err, &validationErrors):
+		return apis.NewBadRequestError("Failed to validate the item.", validationErrors)
+	default:
+		return apis.NewBadRequestError("", err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/spf13/cobra"
+)
+
+// Bang is an entry of the DuckDuckGo `bang.js` list.
+type Bang struct {
+	Trigger     string `json:"t"`
+	Name        string `json:"s"`
+	URL         string `json:"u"`
+	Domain      string `json:"d"`
+	Category    string `json:"c"`
+	Subcategory string `json:"sc"`
+}
+
+func newImportBangsCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	var dryRun bool
+	var tag string
+	command := &cobra.Command{
+		Use:   "bangs <bang.json>",
+		Short: "Imports items from a DuckDuckGo bang list",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			var bangs []Bang
+			if err := json.Unmarshal(data, &bangs); err != nil {
+				return err
+			}
+			items := make([]Item, 0, len(bangs))
+			for _, bang := range bangs {
+				if item, ok := bang.Item(tag); ok {
+					items = append(items, item)
+				}
+			}
+			report, err := importItems(pb, items, dryRun)
+			if err != nil {
+				return err
+			}
+			report.Print(cmd.OutOrStdout(), dryRun)
+			return nil
+		},
+	}
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "only print what would be imported")
+	command.Flags().StringVar(&tag, "tag", "bang", "tag added to every imported item")
+	return command
+}
+
+// Item converts the bang into an item, the `{{{s}}}` search terms become
+// a `{query}` placeholder. Bangs pointing to DuckDuckGo itself are skipped.
+func (b Bang) Item(tag string) (Item, bool) {
+	if b.Trigger == "" || strings.ContainsAny(b.Trigger, " \t") {
+		return Item{}, false
+	}
+	if !strings.HasPrefix(b.URL, "http://") && !strings.HasPrefix(b.URL, "https://") {
+		return Item{}, false
+	}
+	tags := make([]string, 0, 3)
+	for _, t := range []string{tag, b.Category, b.Subcategory} {
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return Item{
+		Name:  b.Name,
+		Alias: b.Trigger,
+		URL:   searchTermsPlaceholder(b.URL, "{{{s}}}"),
+		Tags:  tags,
+	}, true
+}
+
+// searchTermsPlaceholder replaces the search terms token of other tools with
+// a `{query}` placeholder, the ones in the path keep the slashes.
+func searchTermsPlaceholder(u string, token string) string {
+	placeholder := "{query}"
+	if pos := strings.Index(u, token); pos >= 0 && !strings.Contains(u[:pos], "?") {
+		placeholder = "{query:path}"
+	}
+	return strings.ReplaceAll(u, token, placeholder)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBangItem(t *testing.T) {
+	tests := []struct {
+		name string
+		bang Bang
+		ok   bool
+		url  string
+		tags []string
+	}{
+		{
+			name: "query",
+			bang: Bang{Trigger: "w", Name: "Wikipedia", URL: "https://en.wikipedia.org/w/index.php?search={{{s}}}", Category: "Research", Subcategory: "Reference"},
+			ok:   true,
+			url:  "https://en.wikipedia.org/w/index.php?search={query}",
+			tags: []string{"bang", "Research", "Reference"},
+		},
+		{
+			name: "path",
+			bang: Bang{Trigger: "ghr", Name: "GitHub repo", URL: "https://github.com/{{{s}}}"},
+			ok:   true,
+			url:  "https://github.com/{query:path}",
+			tags: []string{"bang"},
+		},
+		{
+			name: "duckduckgo itself",
+			bang: Bang{Trigger: "images", URL: "/?q={{{s}}}&ia=images"},
+		},
+		{
+			name: "javascript",
+			bang: Bang{Trigger: "js", URL: "javascript:alert('{{{s}}}')"},
+		},
+		{
+			name: "no trigger",
+			bang: Bang{URL: "https://example.com/?q={{{s}}}"},
+		},
+		{
+			name: "trigger with spaces",
+			bang: Bang{Trigger: "a b", URL: "https://example.com/?q={{{s}}}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, ok := tt.bang.Item("bang")
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if item.Alias != tt.bang.Trigger || item.Name != tt.bang.Name {
+				t.Errorf("alias, name = %q, %q, want %q, %q", item.Alias, item.Name, tt.bang.Trigger, tt.bang.Name)
+			}
+			if item.URL != tt.url {
+				t.Errorf("url = %q, want %q", item.URL, tt.url)
+			}
+			if !slices.Equal(item.Tags, tt.tags) {
+				t.Errorf("tags = %q, want %q", item.Tags, tt.tags)
+			}
+		})
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{q: "rust traits", want: []string{"rust", "traits"}},
+		// An unknown bang is dropped, the rest goes to the search engine
+		{q: "rust traits !nope", want: []string{"rust", "traits"}},
+		{q: "!nope", want: []string{}},
+		{q: `"!nope" traits`, want: []string{"!nope", "traits"}},
+	}
+	for _, tt := range tests {
+		if got := parseQuery(tt.q).SearchTerms(); !slices.Equal(got, tt.want) {
+			t.Errorf("SearchTerms(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/spf13/cobra"
+	nethtml "golang.org/x/net/html"
+)
+
+// Bookmark is a link from a Netscape bookmark file, the format which every
+// browser uses to export and import bookmarks.
+type Bookmark struct {
+	Title string
+	URL   string
+	// Keyword is the Firefox `SHORTCUTURL`, it becomes the alias
+	Keyword string
+	// Tags are the names of the folders containing the bookmark followed by
+	// its own Firefox `TAGS`
+	Tags []string
+}
+
+func newImportBookmarksCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	var dryRun bool
+	command := &cobra.Command{
+		Use:   "bookmarks <bookmarks.html>",
+		Short: "Imports items from a browser bookmarks export",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			report, err := importBookmarks(pb, file, dryRun)
+			if err != nil {
+				return err
+			}
+			report.Print(cmd.OutOrStdout(), dryRun)
+			return nil
+		},
+	}
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "only print what would be imported")
+	return command
+}
+
+func newExportBookmarksCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	return &cobra.Command{
+		Use:   "bookmarks",
+		Short: "Exports items as a browser bookmarks file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			items, err := getAllItems(pb, "", "")
+			if err != nil {
+				return err
+			}
+			return writeBookmarks(cmd.OutOrStdout(), items)
+		},
+	}
+}
+
+func importBookmarks(pb *pocketbase.PocketBase, r io.Reader, dryRun bool) (ImportReport, error) {
+	bookmarks, err := parseBookmarks(r)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	items := make([]Item, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		if item, ok := bookmark.Item(); ok {
+			items = append(items, item)
+		}
+	}
+	return importItems(pb, items, dryRun)
+}
+
+// parseBookmarks reads all links from a bookmarks file, folders nest in
+// `<DL>` lists and are named by the `<H3>` right before the list.
+func parseBookmarks(r io.Reader) ([]Bookmark, error) {
+	bookmarks := make([]Bookmark, 0)
+	folders := make([]string, 0)
+	var folder string
+	var current *Bookmark
+	var text strings.Builder
+	inFolderName := false
+	z := nethtml.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case nethtml.ErrorToken:
+			if z.Err() == io.EOF {
+				return bookmarks, nil
+			}
+			return bookmarks, z.Err()
+		case nethtml.TextToken:
+			if current != nil || inFolderName {
+				text.Write(z.Text())
+			}
+		case nethtml.StartTagToken:
+			token := z.Token()
+			switch token.Data {
+			case "h3":
+				inFolderName = true
+				text.Reset()
+				// The toolbar and the other special folders aren't
+				// meaningful tags
+				for _, attr := range token.Attr {
+					if attr.Key == "personal_toolbar_folder" || attr.Key == "unfiled_bookmarks_folder" {
+						inFolderName = false
+					}
+				}
+			case "dl":
+				folders = append(folders, folder)
+				folder = ""
+			case "a":
+				current = &Bookmark{}
+				text.Reset()
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "href":
+						current.URL = attr.Val
+					case "shortcuturl":
+						current.Keyword = attr.Val
+					case "tags":
+						current.Tags = strings.Split(attr.Val, ",")
+					}
+				}
+			}
+		case nethtml.EndTagToken:
+			token := z.Token()
+			switch token.Data {
+			case "h3":
+				if inFolderName {
+					folder = strings.TrimSpace(text.String())
+				}
+				inFolderName = false
+			case "dl":
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+			case "a":
+				if current == nil {
+					continue
+				}
+				current.Title = strings.TrimSpace(text.String())
+				tags := make([]string, 0, len(folders)+len(current.Tags))
+				for _, f := range folders {
+					if f != "" {
+						tags = append(tags, f)
+					}
+				}
+				current.Tags = append(tags, current.Tags...)
+				bookmarks = append(bookmarks, *current)
+				current = nil
+			}
+		}
+	}
+}
+
+// Item converts the bookmark into an item. Bookmarks without a keyword get
+// an alias made from their title or, when it is empty, from the host.
+// Bookmarklets and browser specific URLs are skipped.
+func (b Bookmark) Item() (Item, bool) {
+	// The `%s` keyword placeholder isn't a valid escape in a path
+	u, err := url.Parse(strings.ReplaceAll(b.URL, "%s", "s"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return Item{}, false
+	}
+	alias := b.Keyword
+	if alias == "" {
+		alias = slugify(b.Title)
+	}
+	if alias == "" {
+		alias = slugify(u.Hostname())
+	}
+	tags := make([]string, 0, len(b.Tags))
+	seen := make(map[string]bool)
+	for _, tag := range b.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return Item{
+		Name:  b.Title,
+		Alias: alias,
+		URL:   b.URL,
+		Tags:  tags,
+	}, true
+}
+
+// writeBookmarks writes the items as a flat bookmarks file. Aliases become
+// keywords and URL templates use the `%s` keyword placeholder. Keywords
+// can't contain spaces, so nested items are written without them.
+func writeBookmarks(w io.Writer, items []Item) error {
+	_, err := io.WriteString(w, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		title := item.Name
+		if title == "" {
+			title = item.Path
+		}
+		attrs := fmt.Sprintf(`HREF="%s"`, html.EscapeString(compileTemplate(item.URL).Keyword()))
+		if item.Parent == "" {
+			attrs += fmt.Sprintf(` SHORTCUTURL="%s"`, html.EscapeString(item.Alias))
+		}
+		if len(item.Tags) > 0 {
+			attrs += fmt.Sprintf(` TAGS="%s"`, html.EscapeString(strings.Join(item.Tags, ",")))
+		}
+		if _, err := fmt.Fprintf(w, "    <DT><A %s>%s</A>\n", attrs, html.EscapeString(title)); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "</DL><p>\n")
+	return err
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testBookmarksFile = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks Menu</H1>
+<DL><p>
+    <DT><A HREF="https://top.example/">Top level</A>
+    <DT><H3 PERSONAL_TOOLBAR_FOLDER="true">Bookmarks Toolbar</H3>
+    <DL><p>
+        <DT><A HREF="https://github.com/search?q=%s" SHORTCUTURL="gh" TAGS="code,git">GitHub</A>
+        <DT><H3>Work</H3>
+        <DL><p>
+            <DT><A HREF="https://jira.example/browse/%s" SHORTCUTURL="j">Jira &amp; co</A>
+            <DT><H3>Docs</H3>
+            <DL><p>
+                <DT><A HREF="https://docs.example/" TAGS="work, docs">Team docs</A>
+            </DL><p>
+            <DT><A HREF="https://wiki.example/">Wiki</A>
+        </DL><p>
+    </DL><p>
+    <DT><H3 UNFILED_BOOKMARKS_FOLDER="true">Other Bookmarks</H3>
+    <DL><p>
+        <DT><A HREF="javascript:alert(1)">Bookmarklet</A>
+        <DT><A HREF="https://news.example/"></A>
+    </DL><p>
+</DL><p>
+`
+
+func TestParseBookmarks(t *testing.T) {
+	bookmarks, err := parseBookmarks(strings.NewReader(testBookmarksFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Bookmark{
+		{Title: "Top level", URL: "https://top.example/", Tags: []string{}},
+		// The toolbar isn't a tag, the Firefox tags follow the folders
+		{Title: "GitHub", URL: "https://github.com/search?q=%s", Keyword: "gh", Tags: []string{"code", "git"}},
+		{Title: "Jira & co", URL: "https://jira.example/browse/%s", Keyword: "j", Tags: []string{"Work"}},
+		{Title: "Team docs", URL: "https://docs.example/", Tags: []string{"Work", "Docs", "work", " docs"}},
+		// The folder stack is popped after a nested folder ends
+		{Title: "Wiki", URL: "https://wiki.example/", Tags: []string{"Work"}},
+		{Title: "Bookmarklet", URL: "javascript:alert(1)", Tags: []string{}},
+		{Title: "", URL: "https://news.example/", Tags: []string{}},
+	}
+	if !reflect.DeepEqual(bookmarks, want) {
+		t.Errorf("parseBookmarks =\n%+v\nwant\n%+v", bookmarks, want)
+	}
+}
+
+func TestBookmarkItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		bookmark Bookmark
+		ok       bool
+		alias    string
+		tags     []string
+	}{
+		{
+			name:     "keyword",
+			bookmark: Bookmark{Title: "GitHub", URL: "https://github.com/search?q=%s", Keyword: "gh"},
+			ok:       true,
+			alias:    "gh",
+			tags:     []string{},
+		},
+		{
+			name:     "keyword placeholder in the path",
+			bookmark: Bookmark{Title: "Jira", URL: "https://jira.example/browse/%s", Keyword: "j"},
+			ok:       true,
+			alias:    "j",
+			tags:     []string{},
+		},
+		{
+			name:     "title slug",
+			bookmark: Bookmark{Title: "Jira & co", URL: "https://jira.example/"},
+			ok:       true,
+			alias:    "jira-co",
+			tags:     []string{},
+		},
+		{
+			name:     "host slug",
+			bookmark: Bookmark{URL: "https://news.example.com/"},
+			ok:       true,
+			alias:    "news-example-com",
+			tags:     []string{},
+		},
+		{
+			name:     "tags are trimmed and deduplicated",
+			bookmark: Bookmark{Title: "Docs", URL: "https://docs.example/", Tags: []string{"Work", "Docs", " work", "Work", " "}},
+			ok:       true,
+			alias:    "docs",
+			tags:     []string{"Work", "Docs", "work"},
+		},
+		{
+			name:     "bookmarklet",
+			bookmark: Bookmark{Title: "Bookmarklet", URL: "javascript:alert(1)"},
+		},
+		{
+			name:     "browser page",
+			bookmark: Bookmark{Title: "Settings", URL: "about:preferences"},
+		},
+		{
+			name:     "place query",
+			bookmark: Bookmark{Title: "Recent", URL: "place:sort=8&maxResults=10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, ok := tt.bookmark.Item()
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if item.Alias != tt.alias || item.Name != tt.bookmark.Title || item.URL != tt.bookmark.URL {
+				t.Errorf("item = %+v, want alias %q", item, tt.alias)
+			}
+			if !reflect.DeepEqual([]string(item.Tags), tt.tags) {
+				t.Errorf("tags = %q, want %q", item.Tags, tt.tags)
+			}
+		})
+	}
+}
+
+func TestBookmarksRoundTrip(t *testing.T) {
+	items := []Item{
+		{Alias: "gh", Name: "GitHub", URL: "https://github.com/search?q={query}", Tags: []string{"code", "git"}, Path: "gh"},
+		{Alias: "j", Name: `Jira "core" & co`, URL: "https://jira.example/browse/{project=CORE}-{id}", Tags: []string{}, Path: "j"},
+		{Alias: "home", URL: "https://example.com/", Tags: []string{}, Path: "home"},
+		// Keywords can't have spaces, nested items get their alias from the title
+		{Alias: "pr", Parent: "gh", Name: "Pull requests", URL: "https://github.com/pulls", Tags: []string{}, Path: "gh pr"},
+	}
+	var buf bytes.Buffer
+	if err := writeBookmarks(&buf, items); err != nil {
+		t.Fatal(err)
+	}
+	bookmarks, err := parseBookmarks(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]Item, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		item, ok := bookmark.Item()
+		if !ok {
+			t.Fatalf("%+v was skipped", bookmark)
+		}
+		got = append(got, item)
+	}
+	want := []Item{
+		{Alias: "gh", Name: "GitHub", URL: "https://github.com/search?q=%s", Tags: []string{"code", "git"}},
+		{Alias: "j", Name: `Jira "core" & co`, URL: "https://jira.example/browse/CORE-%s", Tags: []string{}},
+		{Alias: "home", Name: "home", URL: "https://example.com/", Tags: []string{}},
+		{Alias: "pull-requests", Name: "Pull requests", URL: "https://github.com/pulls", Tags: []string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip =\n%+v\nwant\n%+v", got, want)
+	}
+	// The keyword URLs expand the same way as the original templates
+	for i, item := range got {
+		if a, b := compileTemplate(item.URL).Expand([]string{"12"}), compileTemplate(items[i].URL).Expand([]string{"12"}); a != b {
+			t.Errorf("%s expands to %q, want %q", item.Alias, a, b)
+		}
+	}
+}
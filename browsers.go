@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/spf13/cobra"
+)
+
+// BrowserKeyword is a Chrome site search or a Firefox keyword bookmark.
+type BrowserKeyword struct {
+	Keyword string `db:"keyword"`
+	Name    string `db:"name"`
+	URL     string `db:"url"`
+}
+
+// Chrome keeps its site searches in the `keywords` table of `Web Data`.
+const CHROME_KEYWORDS_QUERY = "SELECT keyword, short_name AS name, url FROM keywords WHERE keyword != '' AND url != ''"
+
+// Firefox keeps keyword bookmarks in `places.sqlite`, the ones which send
+// the query in a POST body can't be expressed as a link.
+const FIREFOX_KEYWORDS_QUERY = `SELECT k.keyword, COALESCE(p.title, '') AS name, p.url
+	FROM moz_keywords k JOIN moz_places p ON p.id = k.place_id
+	WHERE k.keyword != '' AND COALESCE(k.post_data, '') = ''`
+
+// chromeParameterRegex matches the other Chrome template parameters, ex.
+// `{google:RLZ}` or `{inputEncoding}`, which are dropped.
+var chromeParameterRegex = regexp.MustCompile(`\{[A-Za-z]+(:[A-Za-z]+)?\??\}`)
+
+func newImportBrowserCommand(pb *pocketbase.PocketBase, browser string, query string) *cobra.Command {
+	var dryRun bool
+	var tag string
+	command := &cobra.Command{
+		Use:   fmt.Sprintf("%s <profile database>", browser),
+		Short: fmt.Sprintf("Imports keywords from a %s profile", browser),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keywords, err := readBrowserKeywords(args[0], query)
+			if err != nil {
+				return err
+			}
+			items := make([]Item, 0, len(keywords))
+			for _, keyword := range keywords {
+				if item, ok := keyword.Item(tag); ok {
+					items = append(items, item)
+				}
+			}
+			// Conflicts are reported before anything is written
+			report, err := importItems(pb, items, true)
+			if err != nil {
+				return err
+			}
+			printConflicts(pb, cmd.OutOrStdout(), report.Duplicates)
+			if !dryRun {
+				report, err = importItems(pb, items, false)
+				if err != nil {
+					return err
+				}
+			}
+			report.Print(cmd.OutOrStdout(), dryRun)
+			return nil
+		},
+	}
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "only print what would be imported")
+	command.Flags().StringVar(&tag, "tag", browser, "tag added to every imported item")
+	return command
+}
+
+// readBrowserKeywords reads a copy of the database, because browsers keep
+// their databases locked while running.
+func readBrowserKeywords(path string, query string) ([]BrowserKeyword, error) {
+	dir, err := os.MkdirTemp("", "links-import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "profile.sqlite")
+	if err := copyFile(path, dst); err != nil {
+		return nil, err
+	}
+	// Recent changes can still be in the write-ahead log
+	if _, err := os.Stat(path + "-wal"); err == nil {
+		if err := copyFile(path+"-wal", dst+"-wal"); err != nil {
+			return nil, err
+		}
+	}
+	db, err := dbx.Open(BROWSER_DB_DRIVER, dst)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	keywords := make([]BrowserKeyword, 0)
+	err = db.NewQuery(query).All(&keywords)
+	return keywords, err
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Item converts the keyword into an item, Chrome `{searchTerms}` and Firefox
+// `%s` and `%S` become a `{query}` placeholder.
+func (k BrowserKeyword) Item(tag string) (Item, bool) {
+	if strings.ContainsAny(k.Keyword, " \t") {
+		return Item{}, false
+	}
+	u := strings.ReplaceAll(k.URL, "{google:baseURL}", "https://www.google.com/")
+	u = searchTermsPlaceholder(u, "{searchTerms}")
+	u = chromeParameterRegex.ReplaceAllStringFunc(u, func(s string) string {
+		if strings.HasPrefix(s, "{query") {
+			return s
+		}
+		return ""
+	})
+	u = searchTermsPlaceholder(u, "%s")
+	// Firefox doesn't escape the `%S` terms, which don't work with
+	// arguments as they are
+	u = searchTermsPlaceholder(u, "%S")
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return Item{}, false
+	}
+	tags := []string{}
+	if tag != "" {
+		tags = append(tags, tag)
+	}
+	return Item{
+		Name:  k.Name,
+		Alias: k.Keyword,
+		URL:   u,
+		Tags:  tags,
+	}, true
+}
+
+// printConflicts lists the imported aliases which already exist with
+// a different URL, the ones with the same URL were imported before.
+func printConflicts(pb *pocketbase.PocketBase, w io.Writer, duplicates []Item) {
+	for _, item := range duplicates {
+		for _, existing := range getItemsByExactMatch(pb, Item{}, item.Alias, "") {
+			if existing.URL != item.URL {
+				fmt.Fprintf(w, "~ %s\t%s (exists with %s)\n", item.Alias, item.URL, existing.URL)
+			}
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build cgo
+
+package main
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// BROWSER_DB_DRIVER is the same SQLite driver that PocketBase uses in cgo builds.
+const BROWSER_DB_DRIVER = "sqlite3"
@@ -0,0 +1,10 @@
+//go:build !cgo
+
+package main
+
+import (
+	_ "modernc.org/sqlite"
+)
+
+// BROWSER_DB_DRIVER is the same SQLite driver that PocketBase uses in pure Go builds.
+const BROWSER_DB_DRIVER = "sqlite"
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBrowserKeywordItem(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyword BrowserKeyword
+		ok      bool
+		url     string
+	}{
+		{
+			name:    "chrome",
+			keyword: BrowserKeyword{Keyword: "w", Name: "Wikipedia", URL: "https://en.wikipedia.org/w/index.php?search={searchTerms}"},
+			ok:      true,
+			url:     "https://en.wikipedia.org/w/index.php?search={query}",
+		},
+		{
+			name:    "chrome parameters",
+			keyword: BrowserKeyword{Keyword: "g", Name: "Google", URL: "{google:baseURL}search?q={searchTerms}&{google:RLZ}ie={inputEncoding}"},
+			ok:      true,
+			url:     "https://www.google.com/search?q={query}&ie=",
+		},
+		{
+			name:    "firefox query",
+			keyword: BrowserKeyword{Keyword: "gh", Name: "GitHub", URL: "https://github.com/search?q=%s"},
+			ok:      true,
+			url:     "https://github.com/search?q={query}",
+		},
+		{
+			name:    "firefox path",
+			keyword: BrowserKeyword{Keyword: "ghr", URL: "https://github.com/%s"},
+			ok:      true,
+			url:     "https://github.com/{query:path}",
+		},
+		{
+			name:    "firefox unescaped query",
+			keyword: BrowserKeyword{Keyword: "mdn", URL: "https://developer.mozilla.org/search?q=%S"},
+			ok:      true,
+			url:     "https://developer.mozilla.org/search?q={query}",
+		},
+		{
+			name:    "firefox unescaped path",
+			keyword: BrowserKeyword{Keyword: "npm", URL: "https://www.npmjs.com/package/%S"},
+			ok:      true,
+			url:     "https://www.npmjs.com/package/{query:path}",
+		},
+		{
+			name:    "without search terms",
+			keyword: BrowserKeyword{Keyword: "home", URL: "https://example.com/"},
+			ok:      true,
+			url:     "https://example.com/",
+		},
+		{
+			name:    "keyword with spaces",
+			keyword: BrowserKeyword{Keyword: "a b", URL: "https://example.com/?q=%s"},
+		},
+		{
+			name:    "browser page",
+			keyword: BrowserKeyword{Keyword: "h", URL: "chrome://history/?q={searchTerms}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, ok := tt.keyword.Item("chrome")
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if item.URL != tt.url {
+				t.Errorf("url = %q, want %q", item.URL, tt.url)
+			}
+			if item.Alias != tt.keyword.Keyword || item.Name != tt.keyword.Name || !slices.Equal(item.Tags, []string{"chrome"}) {
+				t.Errorf("item = %+v", item)
+			}
+			if err := validateTemplate(item.URL); err != nil {
+				t.Errorf("invalid url: %v", err)
+			}
+		})
+	}
+}
+
+func TestBrowserKeywordItemExpands(t *testing.T) {
+	item, _ := BrowserKeyword{Keyword: "npm", URL: "https://www.npmjs.com/package/%S"}.Item("")
+	if got := compileTemplate(item.URL).Expand([]string{"@types/node"}); got != "https://www.npmjs.com/package/@types/node" {
+		t.Errorf("Expand = %q", got)
+	}
+	if len(item.Tags) != 0 {
+		t.Errorf("tags = %q, want none", item.Tags)
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"github.com/spf13/cobra"
+)
+
+// DEVICE_TOKEN_TTL is how long new device tokens are valid.
+const DEVICE_TOKEN_TTL = 365 * 24 * time.Hour
+
+// DEVICE_TOKEN_LENGTH is the length of the generated device tokens.
+const DEVICE_TOKEN_LENGTH = 32
+
+// PAIRING_CODE_TTL is how long a pairing code can be entered on the login
+// page of the new device.
+const PAIRING_CODE_TTL = 10 * time.Minute
+
+// PAIRING_CODE_ALPHABET leaves out the characters which are easy to confuse.
+const PAIRING_CODE_ALPHABET = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const PAIRING_CODE_LENGTH = 8
+
+var errDeviceNameTaken = errors.New("a device with this name already exists")
+
+// LAST_SEEN_INTERVAL limits how often the last use of a device is saved.
+const LAST_SEEN_INTERVAL = time.Minute
+
+// Device is authenticated by its token, only the hash of the token is
+// stored. Devices can expire or be revoked. The user of the device has
+// access to their personal items in addition to the team items.
+type Device struct {
+	ID        string         `db:"id"`
+	Name      string         `db:"name"`
+	User      string         `db:"user"`
+	TokenHash string         `db:"token_hash"`
+	Expires   types.DateTime `db:"expires"`
+	LastSeen  types.DateTime `db:"last_seen"`
+	Revoked   bool           `db:"revoked"`
+}
+
+// DeviceUsage is a device with the number of its expansions.
+type DeviceUsage struct {
+	Device
+	Email      string `db:"email"`
+	Expansions int    `db:"expansions"`
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// registerDeviceHooks replaces the tokens with their hashes whenever they
+// are saved, ex. from the admin UI, the `token` field is only an input.
+func registerDeviceHooks(pb *pocketbase.PocketBase) {
+	hash := func(e *core.ModelEvent) error {
+		record, ok := e.Model.(*models.Record)
+		if !ok {
+			return nil
+		}
+		if token := record.GetString("token"); token != "" {
+			record.Set("token_hash", hashToken(token))
+			record.Set("token", "")
+			if record.GetDateTime("expires").IsZero() {
+				expires, _ := types.ParseDateTime(time.Now().Add(DEVICE_TOKEN_TTL))
+				record.Set("expires", expires)
+			}
+		}
+		if record.GetString("token_hash") == "" {
+			return validation.Errors{"token": validation.ErrRequired}
+		}
+		return nil
+	}
+	pb.OnModelBeforeCreate("devices").Add(hash)
+	pb.OnModelBeforeUpdate("devices").Add(hash)
+
+	// The users can see their devices through the REST API, but not the
+	// hashes of their tokens
+	pb.OnRecordsListRequest("devices").Add(func(e *core.RecordsListEvent) error {
+		for _, record := range e.Records {
+			record.Set("token_hash", "")
+		}
+		return nil
+	})
+	pb.OnRecordViewRequest("devices").Add(func(e *core.RecordViewEvent) error {
+		e.Record.Set("token_hash", "")
+		return nil
+	})
+}
+
+// getDeviceByToken returns the device of the token unless it has expired
+// or has been revoked.
+func getDeviceByToken(pb *pocketbase.PocketBase, token string) (Device, bool) {
+	devices := []Device{}
+	pb.Dao().DB().
+		NewQuery("SELECT id, name, user, token_hash, expires, last_seen, revoked FROM devices WHERE token_hash = {:hash} AND revoked = FALSE AND (expires = '' OR expires > {:now})").
+		Bind(dbx.Params{
+			"hash": hashToken(token),
+			"now":  types.NowDateTime().String(),
+		}).
+		All(&devices)
+	if len(devices) != 1 {
+		return Device{}, false
+	}
+	return devices[0], true
+}
+
+// touchDevice saves the last use of the device, at most once a minute.
+func touchDevice(pb *pocketbase.PocketBase, device Device) error {
+	if time.Since(device.LastSeen.Time()) < LAST_SEEN_INTERVAL {
+		return nil
+	}
+	_, err := pb.Dao().DB().
+		NewQuery("UPDATE devices SET last_seen = {:now} WHERE id = {:id}").
+		Bind(dbx.Params{
+			"id":  device.ID,
+			"now": types.NowDateTime().String(),
+		}).
+		Execute()
+	return err
+}
+
+// getDevicesUsage returns the devices matching the condition, the recently
+// used go first.
+func getDevicesUsage(pb *pocketbase.PocketBase, where dbx.Expression) []DeviceUsage {
+	devices := make([]DeviceUsage, 0)
+	pb.Dao().DB().
+		Select("d.id", "d.name", "d.user", "d.token_hash", "d.expires", "d.last_seen", "d.revoked", "COALESCE(u.email, '') AS email", "COUNT(l.id) AS expansions").
+		From("devices d").
+		LeftJoin("users u", dbx.NewExp("u.id = d.user")).
+		LeftJoin("logs l", dbx.NewExp("l.device = d.id")).
+		Where(where).
+		GroupBy("d.id").
+		OrderBy("d.revoked", "d.last_seen DESC", "d.name").
+		All(&devices)
+	return devices
+}
+
+// findDevice finds the device by its id or by its name.
+func findDevice(dao *daos.Dao, nameOrID string) (*models.Record, error) {
+	if record, err := dao.FindRecordById("devices", nameOrID); err == nil {
+		return record, nil
+	}
+	record, err := dao.FindFirstRecordByData("devices", "name", nameOrID)
+	if err != nil {
+		return nil, fmt.Errorf("device %q not found", nameOrID)
+	}
+	return record, nil
+}
+
+func revokeDevice(dao *daos.Dao, nameOrID string) (*models.Record, error) {
+	record, err := findDevice(dao, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	record.Set("revoked", true)
+	return record, dao.SaveRecord(record)
+}
+
+// rotateDevice replaces the token of the device and makes it valid again,
+// even if it was revoked or has expired.
+func rotateDevice(dao *daos.Dao, nameOrID string) (*models.Record, string, error) {
+	record, err := findDevice(dao, nameOrID)
+	if err != nil {
+		return nil, "", err
+	}
+	token := security.RandomString(DEVICE_TOKEN_LENGTH)
+	expires, _ := types.ParseDateTime(time.Now().Add(DEVICE_TOKEN_TTL))
+	record.Set("token", token)
+	record.Set("expires", expires)
+	record.Set("revoked", false)
+	return record, token, dao.SaveRecord(record)
+}
+
+// userDevicesExp selects the devices of the user, or the devices without a
+// user when it is empty.
+func userDevicesExp(user string) dbx.Expression {
+	return dbx.HashExp{"d.user": user}
+}
+
+// createDevice adds a device of the user with a new token, the token is
+// returned since only its hash is stored.
+func createDevice(dao *daos.Dao, name string, user string) (*models.Record, string, error) {
+	if existing, _ := dao.FindFirstRecordByData("devices", "name", name); existing != nil {
+		return nil, "", errDeviceNameTaken
+	}
+	collection, err := dao.FindCollectionByNameOrId("devices")
+	if err != nil {
+		return nil, "", err
+	}
+	token := security.RandomString(DEVICE_TOKEN_LENGTH)
+	record := models.NewRecord(collection)
+	record.Set("name", name)
+	record.Set("user", user)
+	record.Set("token", token)
+	if err := dao.SaveRecord(record); err != nil {
+		return nil, "", err
+	}
+	return record, token, nil
+}
+
+// createPairingCode allows to add a device of the user with the name by
+// entering the returned code on its login page.
+func createPairingCode(pb *pocketbase.PocketBase, name string, user string) (string, types.DateTime, error) {
+	var code string
+	var expires types.DateTime
+	err := pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		if existing, _ := txDao.FindFirstRecordByData("devices", "name", name); existing != nil {
+			return errDeviceNameTaken
+		}
+		_, err := txDao.DB().
+			NewQuery("DELETE FROM pairings WHERE expires <= {:now}").
+			Bind(dbx.Params{"now": types.NowDateTime().String()}).
+			Execute()
+		if err != nil {
+			return err
+		}
+		collection, err := txDao.FindCollectionByNameOrId("pairings")
+		if err != nil {
+			return err
+		}
+		code = security.RandomStringWithAlphabet(PAIRING_CODE_LENGTH, PAIRING_CODE_ALPHABET)
+		expires, _ = types.ParseDateTime(time.Now().Add(PAIRING_CODE_TTL))
+		record := models.NewRecord(collection)
+		record.Set("code_hash", hashToken(code))
+		record.Set("name", name)
+		record.Set("user", user)
+		record.Set("expires", expires)
+		return txDao.SaveRecord(record)
+	})
+	return formatPairingCode(code), expires, err
+}
+
+// pairDevice exchanges the pairing code for a new device, each code can be
+// used once.
+func pairDevice(pb *pocketbase.PocketBase, code string) (Device, string, bool) {
+	var device Device
+	var token string
+	err := pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		pairing, err := txDao.FindFirstRecordByFilter(
+			"pairings",
+			"code_hash = {:hash} && expires > {:now}",
+			dbx.Params{"hash": hashToken(normalizePairingCode(code)), "now": types.NowDateTime().String()},
+		)
+		if err != nil {
+			return err
+		}
+		if err := txDao.DeleteRecord(pairing); err != nil {
+			return err
+		}
+		record, newToken, err := createDevice(txDao, pairing.GetString("name"), pairing.GetString("user"))
+		if err != nil {
+			return err
+		}
+		device = Device{
+			ID:        record.Id,
+			Name:      record.GetString("name"),
+			User:      record.GetString("user"),
+			TokenHash: record.GetString("token_hash"),
+			Expires:   record.GetDateTime("expires"),
+		}
+		token = newToken
+		return nil
+	})
+	return device, token, err == nil
+}
+
+// formatPairingCode splits the code in halves for readability, ex.
+// `ABCD-2345`.
+func formatPairingCode(code string) string {
+	if len(code) != PAIRING_CODE_LENGTH {
+		return code
+	}
+	return code[:PAIRING_CODE_LENGTH/2] + "-" + code[PAIRING_CODE_LENGTH/2:]
+}
+
+func normalizePairingCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+func newDeviceCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "device",
+		Short: "Manages the devices and their tokens",
+	}
+	command.AddCommand(newDeviceCreateCommand(pb))
+	command.AddCommand(newDeviceListCommand(pb))
+	command.AddCommand(newDeviceRevokeCommand(pb))
+	command.AddCommand(newDeviceRotateCommand(pb))
+	return command
+}
+
+func newDeviceCreateCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	var searchURL bool
+	var user string
+	command := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Adds a device and prints its token, which isn't shown again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userID, err := findUserID(pb.Dao(), user)
+			if err != nil {
+				return err
+			}
+			_, token, err := createDevice(pb.Dao(), args[0], userID)
+			if err != nil {
+				return err
+			}
+			printToken(cmd.OutOrStdout(), pb, token, searchURL)
+			return nil
+		},
+	}
+	command.Flags().BoolVar(&searchURL, "search-url", false, "also print the URL of a browser search engine with the token")
+	command.Flags().StringVar(&user, "user", "", "email or id of the user of the device, without it the device only has the team items")
+	return command
+}
+
+// findUserID returns the id of the user by their email or id, an empty user
+// stays empty.
+func findUserID(dao *daos.Dao, emailOrID string) (string, error) {
+	if emailOrID == "" {
+		return "", nil
+	}
+	if record, err := dao.FindRecordById("users", emailOrID); err == nil {
+		return record.Id, nil
+	}
+	record, err := dao.FindAuthRecordByEmail("users", emailOrID)
+	if err != nil {
+		return "", fmt.Errorf("user %q not found", emailOrID)
+	}
+	return record.Id, nil
+}
+
+func newDeviceListCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Lists the devices with their last use and expansions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printDevices(cmd.OutOrStdout(), getDevicesUsage(pb, dbx.NewExp("1 = 1")))
+		},
+	}
+}
+
+func newDeviceRevokeCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke <name|id>",
+		Short: "Revokes the token of a device",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			record, err := revokeDevice(pb.Dao(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Revoked %s\n", record.GetString("name"))
+			return nil
+		},
+	}
+}
+
+func newDeviceRotateCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	var searchURL bool
+	command := &cobra.Command{
+		Use:   "rotate <name|id>",
+		Short: "Replaces the token of a device and prints the new one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, token, err := rotateDevice(pb.Dao(), args[0])
+			if err != nil {
+				return err
+			}
+			printToken(cmd.OutOrStdout(), pb, token, searchURL)
+			return nil
+		},
+	}
+	command.Flags().BoolVar(&searchURL, "search-url", false, "also print the URL of a browser search engine with the token")
+	return command
+}
+
+// printToken prints the token alone on the first line, so that scripts can
+// read it.
+func printToken(w io.Writer, pb *pocketbase.PocketBase, token string, searchURL bool) {
+	fmt.Fprintln(w, token)
+	if searchURL {
+		fmt.Fprintf(w, "%s/api/expand?q=%%s&token=%s\n", getBaseURL(pb), url.QueryEscape(token))
+	}
+}
+
+func printDevices(w io.Writer, devices []DeviceUsage) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tUSER\tLAST USED\tEXPIRES\tEXPANSIONS\tSTATUS")
+	for _, device := range devices {
+		user := device.Email
+		if user == "" {
+			user = "-"
+		}
+		fmt.Fprintf(
+			tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			device.ID, device.Name, user, formatDeviceDate(device.LastSeen), formatDeviceDate(device.Expires), device.Expansions, device.Status(),
+		)
+	}
+	return tw.Flush()
+}
+
+func formatDeviceDate(date types.DateTime) string {
+	if date.IsZero() {
+		return "never"
+	}
+	return date.Time().Local().Format("2006-01-02 15:04")
+}
+
+func (d Device) Status() string {
+	switch {
+	case d.Revoked:
+		return "revoked"
+	case !d.Expires.IsZero() && d.Expires.Time().Before(time.Now()):
+		return "expired"
+	default:
+		return "active"
+	}
+}
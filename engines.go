@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+)
+
+// Engine is a search engine used when the query doesn't match any alias.
+// Engines are ordered by their position, the first one is the default,
+// unless the device has its own default engine, the others are offered as
+// alternatives.
+type Engine struct {
+	ID       string `db:"id"`
+	Name     string `db:"name"`
+	Alias    string `db:"alias"`
+	URL      string `db:"url"`
+	Position int    `db:"position"`
+}
+
+func (e Engine) Item() Item {
+	return Item{
+		ID:    e.ID,
+		Name:  e.Name,
+		Alias: e.Alias,
+		URL:   e.URL,
+		Path:  e.Alias,
+	}
+}
+
+func getEngineByAlias(pb *pocketbase.PocketBase, alias string) (Engine, bool) {
+	engines := make([]Engine, 0)
+	pb.Dao().DB().
+		NewQuery("SELECT id, name, alias, url, position FROM engines WHERE alias != '' AND alias = {:alias} LIMIT 1").
+		Bind(dbx.Params{
+			"alias": alias,
+		}).
+		All(&engines)
+	if len(engines) == 0 {
+		return Engine{}, false
+	}
+	return engines[0], true
+}
+
+// EngineExpansion is the query expanded with one of the fallback engines.
+type EngineExpansion struct {
+	Name string
+	Expansion
+}
+
+// getFallbackEngines returns all engines in the order they are offered, the
+// default engine of the device comes first and the rest follow by position.
+func getFallbackEngines(pb *pocketbase.PocketBase, deviceId string) []Engine {
+	engines := make([]Engine, 0)
+	pb.Dao().DB().
+		NewQuery("SELECT e.id, e.name, e.alias, e.url, e.position FROM engines e LEFT JOIN devices d ON d.engine = e.id AND d.id = {:device} ORDER BY (CASE WHEN d.id IS NULL THEN 2 ELSE 1 END), e.position, e.created").
+		Bind(dbx.Params{
+			"device": deviceId,
+		}).
+		All(&engines)
+	return engines
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// FEED_LIMIT is the number of recently created items in the feed.
+const FEED_LIMIT = 50
+
+const (
+	FORMAT_ATOM = "atom"
+	FORMAT_RSS  = "rss"
+)
+
+// FeedItem is an item with its creation time, which feeds are ordered by.
+type FeedItem struct {
+	Item
+	Created types.DateTime `db:"created"`
+}
+
+// Feed is rendered either as Atom or as RSS.
+type Feed struct {
+	Title string
+	// URL is the page of the feed, the items link to their expansions
+	URL     string
+	Updated time.Time
+	Items   []FeedItem
+}
+
+// getRecentItems returns the last created items visible to the user, newest
+// first.
+func getRecentItems(pb *pocketbase.PocketBase, limit int, user string) []FeedItem {
+	items := make([]FeedItem, 0)
+	pb.Dao().DB().
+		Select("id", "parent", "owner", "alias", "name", "url", "variants", "tags", "created").
+		From("items").
+		Where(visibleItemsExp(user)).
+		OrderBy("created DESC", "alias").
+		Limit(int64(limit)).
+		All(&items)
+	return withFeedPaths(pb, items)
+}
+
+// getFeedItems adds the creation times to the items, keeping their order.
+func getFeedItems(pb *pocketbase.PocketBase, items []Item) []FeedItem {
+	ids := make([]any, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	rows := make([]FeedItem, 0)
+	pb.Dao().DB().
+		Select("id", "created").
+		From("items").
+		Where(dbx.In("id", ids...)).
+		All(&rows)
+	created := make(map[string]types.DateTime, len(rows))
+	for _, row := range rows {
+		created[row.ID] = row.Created
+	}
+	result := make([]FeedItem, len(items))
+	for i, item := range items {
+		result[i] = FeedItem{Item: item, Created: created[item.ID]}
+	}
+	return result
+}
+
+func withFeedPaths(pb *pocketbase.PocketBase, items []FeedItem) []FeedItem {
+	plain := make([]Item, len(items))
+	for i := range items {
+		plain[i] = items[i].Item
+	}
+	for i, item := range withParentPaths(pb, plain) {
+		items[i].Item = item
+	}
+	return items
+}
+
+func newFeed(title string, url string, items []FeedItem) Feed {
+	feed := Feed{Title: title, URL: url, Items: items}
+	for _, item := range items {
+		if item.Created.Time().After(feed.Updated) {
+			feed.Updated = item.Created.Time()
+		}
+	}
+	return feed
+}
+
+// renderFeed writes the feed in the format from the `format` parameter,
+// Atom is the default.
+func renderFeed(c echo.Context, feed Feed) error {
+	switch c.QueryParam("format") {
+	case FORMAT_RSS:
+		c.Response().Header().Set(echo.HeaderContentType, "application/rss+xml; charset=UTF-8")
+		return c.XML(http.StatusOK, feed.RSS())
+	case "", FORMAT_ATOM:
+		c.Response().Header().Set(echo.HeaderContentType, "application/atom+xml; charset=UTF-8")
+		return c.XML(http.StatusOK, feed.Atom())
+	default:
+		return c.String(http.StatusBadRequest, fmt.Sprintf("unknown format %q", c.QueryParam("format")))
+	}
+}
+
+func (i FeedItem) title() string {
+	if i.Name == "" {
+		return i.Path
+	}
+	return fmt.Sprintf("%s: %s", i.Path, i.Name)
+}
+
+func (i FeedItem) summary() string {
+	summary := i.URL
+	if len(i.Tags) > 0 {
+		summary += "\nTags: " + strings.Join(i.Tags, ", ")
+	}
+	return summary
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Link       atomLink       `xml:"link"`
+	Summary    string         `xml:"summary"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func (f Feed) Atom() any {
+	feed := atomFeed{
+		ID:      f.URL,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: f.URL},
+		Entries: make([]atomEntry, 0, len(f.Items)),
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			ID:      fmt.Sprintf("%s#%s", f.URL, item.ID),
+			Title:   item.title(),
+			Updated: item.Created.Time().UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: expand(item.Item, []string{}).URL},
+			Summary: item.summary(),
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	GUID        rssGUID  `xml:"guid"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+func (f Feed) RSS() any {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.URL,
+			Description:   f.Title,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(f.Items)),
+		},
+	}
+	for _, item := range f.Items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			GUID:        rssGUID{Value: fmt.Sprintf("%s#%s", f.URL, item.ID)},
+			Title:       item.title(),
+			Link:        expand(item.Item, []string{}).URL,
+			Description: item.summary(),
+			PubDate:     item.Created.Time().UTC().Format(time.RFC1123Z),
+			Categories:  item.Tags,
+		})
+	}
+	return feed
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// Frecency combines how often and how recently an alias was used. Every use
+// adds a weight, which doubles each half-life period after the epoch, so the
+// older uses decay relative to the new ones without rewriting the scores.
+// The migration backfilling the scores from the logs uses the same formula.
+var FRECENCY_EPOCH = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const FRECENCY_HALF_LIFE = 30 * 24 * time.Hour
+
+func frecencyWeight(t time.Time) float64 {
+	return math.Exp2(float64(t.Sub(FRECENCY_EPOCH)) / float64(FRECENCY_HALF_LIFE))
+}
+
+// bumpFrecency adds a use of the alias to the global score and to the score
+// of the device.
+func bumpFrecency(dao *daos.Dao, alias string, deviceId string, t time.Time) error {
+	collection, err := dao.FindCollectionByNameOrId("frecency")
+	if err != nil {
+		return err
+	}
+	weight := frecencyWeight(t)
+	devices := []string{""}
+	if deviceId != "" {
+		devices = append(devices, deviceId)
+	}
+	for _, device := range devices {
+		records, err := dao.FindRecordsByExpr("frecency", dbx.HashExp{"alias": alias, "device": device})
+		if err != nil {
+			return err
+		}
+		record := models.NewRecord(collection)
+		if len(records) > 0 {
+			record = records[0]
+		}
+		record.Set("alias", alias)
+		record.Set("device", device)
+		record.Set("score", record.GetFloat("score")+weight)
+		if err := dao.SaveRecord(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getRankingDevice returns the device, which scores are used for ranking,
+// it is empty when the device uses the global scores.
+func getRankingDevice(pb *pocketbase.PocketBase, deviceId string) string {
+	if deviceId == "" {
+		return ""
+	}
+	var personal bool
+	pb.Dao().DB().
+		Select("personal_ranking").
+		From("devices").
+		Where(dbx.HashExp{"id": deviceId}).
+		Row(&personal)
+	if personal {
+		return deviceId
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/spf13/cobra"
+)
+
+func newImportCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "import",
+		Short: "Imports items from other tools",
+	}
+	command.AddCommand(newImportBangsCommand(pb))
+	command.AddCommand(newImportBookmarksCommand(pb))
+	command.AddCommand(newImportBrowserCommand(pb, "chrome", CHROME_KEYWORDS_QUERY))
+	command.AddCommand(newImportBrowserCommand(pb, "firefox", FIREFOX_KEYWORDS_QUERY))
+	return command
+}
+
+func newExportCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Exports items for other tools",
+	}
+	command.AddCommand(newExportBookmarksCommand(pb))
+	command.AddCommand(newExportManifestCommand(pb))
+	return command
+}
+
+// ImportReport describes what an import did or, in a dry run, would do.
+type ImportReport struct {
+	Created []Item
+	// Duplicates are skipped items, which have the same alias as an
+	// existing item or as an item earlier in the same import.
+	Duplicates []Item
+	Failed     map[string]error
+}
+
+func (r ImportReport) Print(w io.Writer, dryRun bool) {
+	verb := "Created"
+	if dryRun {
+		verb = "Would create"
+	}
+	for _, item := range r.Created {
+		fmt.Fprintf(w, "+ %s\t%s\n", item.Alias, item.URL)
+	}
+	for _, item := range r.Duplicates {
+		fmt.Fprintf(w, "= %s\t%s (duplicate alias)\n", item.Alias, item.URL)
+	}
+	for alias, err := range r.Failed {
+		fmt.Fprintf(w, "! %s\t%s\n", alias, err)
+	}
+	fmt.Fprintf(w, "%s %d items, skipped %d duplicates, %d failed\n", verb, len(r.Created), len(r.Duplicates), len(r.Failed))
+}
+
+// importItems creates top level items in a single transaction, skipping the
+// aliases which already exist.
+func importItems(pb *pocketbase.PocketBase, items []Item, dryRun bool) (ImportReport, error) {
+	report := ImportReport{Failed: map[string]error{}}
+	seen := getAliasSet(pb)
+	err := pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		for _, item := range items {
+			if seen[item.Alias] {
+				report.Duplicates = append(report.Duplicates, item)
+				continue
+			}
+			seen[item.Alias] = true
+			if err := validateItem(txDao, item); err != nil {
+				report.Failed[item.Alias] = err
+				continue
+			}
+			if !dryRun {
+				if _, err := saveNewItem(txDao, item); err != nil {
+					report.Failed[item.Alias] = err
+					continue
+				}
+			}
+			report.Created = append(report.Created, item)
+		}
+		return nil
+	})
+	return report, err
+}
+
+// getAliasSet returns the aliases of all top level team items.
+func getAliasSet(pb *pocketbase.PocketBase) map[string]bool {
+	aliases := make([]string, 0)
+	pb.Dao().DB().
+		Select("alias").
+		From("items").
+		Where(dbx.HashExp{"parent": "", "owner": ""}).
+		Column(&aliases)
+	set := make(map[string]bool, len(aliases))
+	for _, alias := range aliases {
+		set[alias] = true
+	}
+	return set
+}
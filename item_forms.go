@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/biozz/links/web"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+)
+
+// registerItemForms adds the pages for creating, editing, duplicating and
+// deleting items in the UI, the forms are submitted by htmx.
+func registerItemForms(router *echo.Echo, pb *pocketbase.PocketBase, tmpls *web.Templates, authMiddleware *AuthMiddleware) {
+	router.GET("/new", func(c echo.Context) error {
+		item := Item{Alias: c.QueryParam("alias")}
+		// Duplicating copies everything except for the alias
+		if from := c.QueryParam("from"); from != "" {
+			original, err := getItemByID(pb, from, getUserID(c))
+			if err != nil {
+				return c.String(http.StatusNotFound, err.Error())
+			}
+			item = original
+			item.ID = ""
+			item.Alias = c.QueryParam("alias")
+		}
+		return tmpls.RenderEcho(c.Response().Writer, "new", newItemForm(item, getUserID(c)), c)
+	}, authMiddleware.Process)
+
+	router.POST("/items", func(c echo.Context) error {
+		var newItem Item
+		if err := c.Bind(&newItem); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		newItem.Tags = splitTags(newItem.Tags)
+		if err := bindFormVariants(c, &newItem); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		// The owner only comes from the checkbox, items can't be created
+		// for other users
+		newItem.Owner = ""
+		if c.FormValue("personal") != "" {
+			newItem.Owner = getUserID(c)
+		}
+		_, err := createItem(pb, newItem)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Response().Header().Set("HX-Redirect", "/")
+		return c.String(http.StatusOK, "ok")
+	}, authMiddleware.Process)
+
+	router.GET("/items/:id/edit", func(c echo.Context) error {
+		item, err := getItemByID(pb, c.PathParam("id"), getUserID(c))
+		if err != nil {
+			return c.String(http.StatusNotFound, err.Error())
+		}
+		return tmpls.RenderEcho(c.Response().Writer, "new", newItemForm(item, getUserID(c)), c)
+	}, authMiddleware.Process)
+
+	router.PATCH("/items/:id", func(c echo.Context) error {
+		item, err := getItemByID(pb, c.PathParam("id"), getUserID(c))
+		if err != nil {
+			return c.String(http.StatusNotFound, err.Error())
+		}
+		owner := item.Owner
+		if err := c.Bind(&item); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		item.ID = c.PathParam("id")
+		// Personal items stay personal and team items stay shared
+		item.Owner = owner
+		item.Tags = splitTags(item.Tags)
+		if err := bindFormVariants(c, &item); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if _, err := updateItem(pb, item); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		c.Response().Header().Set("HX-Redirect", "/")
+		return c.String(http.StatusOK, "ok")
+	}, authMiddleware.Process)
+
+	router.DELETE("/items/:id", func(c echo.Context) error {
+		if err := deleteItem(pb, c.PathParam("id"), getUserID(c)); err != nil {
+			if errors.Is(err, errForeignChildren) {
+				return c.String(http.StatusConflict, err.Error())
+			}
+			return c.String(http.StatusNotFound, err.Error())
+		}
+		// The element is replaced with the empty response
+		return c.String(http.StatusOK, "")
+	}, authMiddleware.Process)
+}
+
+// bindFormVariants replaces the variants of the item with the ones from the
+// `variants` field of the form, when the form has it.
+func bindFormVariants(c echo.Context, item *Item) error {
+	params, err := c.FormValues()
+	if err != nil {
+		return err
+	}
+	if !params.Has("variants") {
+		return nil
+	}
+	variants, err := parseVariants(params.Get("variants"))
+	if err != nil {
+		return err
+	}
+	item.Variants = variants
+	return nil
+}
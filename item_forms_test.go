@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateItemWithVariants(t *testing.T) {
+	pb, router := newTestApp(t)
+	token := newTestDevice(t, pb, "laptop", "")
+	original, err := createItem(pb, Item{
+		Alias:    "gh",
+		Name:     "GitHub",
+		URL:      "https://github.com/search?q={query}",
+		Variants: Variants{0: "https://github.com", 2: "https://github.com/{owner}/{repo}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := submitForm(router, http.MethodGet, "/new?alias=gh2&from="+original.ID, token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /new = %d: %s", rec.Code, rec.Body)
+	}
+	variants := formatVariants(original.Variants)
+	if !strings.Contains(rec.Body.String(), ">"+variants+"</textarea>") {
+		t.Fatalf("the form doesn't have the variants %q:\n%s", variants, rec.Body)
+	}
+
+	rec = submitForm(router, http.MethodPost, "/items", token, url.Values{
+		"alias":    {"gh2"},
+		"name":     {original.Name},
+		"url":      {original.URL},
+		"variants": {variants},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /items = %d: %s", rec.Code, rec.Body)
+	}
+	duplicate := getItemsByExactMatch(pb, Item{}, "gh2", "")
+	if len(duplicate) != 1 {
+		t.Fatalf("gh2 = %v", duplicate)
+	}
+	if !reflect.DeepEqual(duplicate[0].Variants, original.Variants) {
+		t.Errorf("variants = %v, want %v", duplicate[0].Variants, original.Variants)
+	}
+
+	// Emptying the field on the edit form removes the variants
+	rec = submitForm(router, http.MethodPatch, "/items/"+duplicate[0].ID, token, url.Values{
+		"alias":    {"gh2"},
+		"url":      {original.URL},
+		"variants": {""},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH /items = %d: %s", rec.Code, rec.Body)
+	}
+	if item, _ := getItemByID(pb, duplicate[0].ID, ""); item.Variants != nil {
+		t.Errorf("variants = %v, want none", item.Variants)
+	}
+}
+
+func TestItemFormsIgnoreOwner(t *testing.T) {
+	pb, router := newTestApp(t)
+	alice := newTestUser(t, pb, "alice")
+	bob := newTestUser(t, pb, "bob")
+	token := newTestDevice(t, pb, "laptop", alice)
+
+	create := []struct {
+		name     string
+		submit   func() int
+		alias    string
+		personal bool
+	}{
+		{
+			name: "form",
+			submit: func() int {
+				return submitForm(router, http.MethodPost, "/items", token, url.Values{
+					"alias": {"form"}, "url": {"https://example.com"}, "owner": {bob},
+				}).Code
+			},
+			alias: "form",
+		},
+		{
+			name: "json",
+			submit: func() int {
+				return submitJSON(router, http.MethodPost, "/items", token,
+					`{"alias": "json", "url": "https://example.com", "owner": "`+bob+`"}`).Code
+			},
+			alias: "json",
+		},
+		{
+			name: "personal",
+			submit: func() int {
+				return submitForm(router, http.MethodPost, "/items", token, url.Values{
+					"alias": {"personal"}, "url": {"https://example.com"}, "owner": {bob}, "personal": {"on"},
+				}).Code
+			},
+			alias:    "personal",
+			personal: true,
+		},
+	}
+	for _, tt := range create {
+		t.Run("create "+tt.name, func(t *testing.T) {
+			if code := tt.submit(); code != http.StatusOK {
+				t.Fatalf("POST /items = %d", code)
+			}
+			items := getItemsByExactMatch(pb, Item{}, tt.alias, alice)
+			want := ""
+			if tt.personal {
+				want = alice
+			}
+			if len(items) != 1 || items[0].Owner != want {
+				t.Errorf("%s = %+v, want owner %q", tt.alias, items, want)
+			}
+		})
+	}
+
+	team, err := createItem(pb, Item{Alias: "team", URL: "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	personal, err := createItem(pb, Item{Alias: "mine", URL: "https://example.com", Owner: alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := []struct {
+		name   string
+		item   Item
+		submit func(path string) int
+	}{
+		{
+			name: "form",
+			item: team,
+			submit: func(path string) int {
+				return submitForm(router, http.MethodPatch, path, token, url.Values{
+					"alias": {team.Alias}, "url": {team.URL}, "owner": {bob},
+				}).Code
+			},
+		},
+		{
+			name: "json",
+			item: team,
+			submit: func(path string) int {
+				return submitJSON(router, http.MethodPatch, path, token, `{"owner": "`+bob+`"}`).Code
+			},
+		},
+		{
+			name: "json personal",
+			item: personal,
+			submit: func(path string) int {
+				return submitJSON(router, http.MethodPatch, path, token, `{"owner": ""}`).Code
+			},
+		},
+	}
+	for _, tt := range update {
+		t.Run("update "+tt.name, func(t *testing.T) {
+			if code := tt.submit("/items/" + tt.item.ID); code != http.StatusOK {
+				t.Fatalf("PATCH /items = %d", code)
+			}
+			item, err := getItemByID(pb, tt.item.ID, alice)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Owner != tt.item.Owner {
+				t.Errorf("owner = %q, want %q", item.Owner, tt.item.Owner)
+			}
+		})
+	}
+}
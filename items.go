@@ -0,0 +1,331 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+var aliasRegex = regexp.MustCompile(`^\S+$`)
+
+var errForeignChildren = errors.New("the item has personal sub-commands of other owners, they have to be deleted first")
+
+// RESERVED_ALIASES can't be used by top-level items, they are either pages
+// of the app or the default search engine. Aliases of the other engines are
+// reserved as well.
+var RESERVED_ALIASES = []string{
+	"_", "g", "new", "login", "logout", "api", "items", "logs", "stats",
+	"static", "expand", "opensearch", "opensearch.xml", "import", "export",
+	"devices",
+}
+
+// UNSAFE_SCHEMES would run in the context of the page that opens the URL.
+var UNSAFE_SCHEMES = []string{"javascript:", "vbscript:", "data:"}
+
+func (i Item) Validate() error {
+	return validation.ValidateStruct(&i,
+		validation.Field(&i.Alias, validation.Required, validation.Match(aliasRegex).Error("must not contain whitespace")),
+		validation.Field(&i.URL, validation.Required, validation.By(validateTemplate)),
+		validation.Field(&i.Variants, validation.By(validateVariants)),
+	)
+}
+
+func validateTemplate(value any) error {
+	source, _ := value.(string)
+	if source == "" {
+		return nil
+	}
+	// URLs with `%s` fall back to the legacy syntax, so their own braces
+	// don't have to be balanced, the same way compileTemplate does it
+	if _, err := parseTemplate(source); err != nil && !strings.Contains(source, "%s") {
+		return validation.NewError("validation_invalid_template", err.Error())
+	}
+	// Browsers ignore whitespace and control characters in the scheme
+	scheme := strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, source))
+	for _, unsafe := range UNSAFE_SCHEMES {
+		if strings.HasPrefix(scheme, unsafe) {
+			return validation.NewError("validation_unsafe_url", "must not use the javascript, vbscript or data scheme")
+		}
+	}
+	return nil
+}
+
+func validateVariants(value any) error {
+	variants, _ := value.(Variants)
+	for arity, source := range variants {
+		if arity < 0 {
+			return validation.NewError("validation_invalid_arity", "Variant keys must be non-negative.")
+		}
+		if err := validateTemplate(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerItemHooks validates items on every save, so that the rules apply
+// to the app, the JSON API and the admin UI alike.
+func registerItemHooks(pb *pocketbase.PocketBase) {
+	validate := func(e *core.ModelEvent) error {
+		record, ok := e.Model.(*models.Record)
+		if !ok {
+			return nil
+		}
+		return validateItem(e.Dao, itemFromRecord(record))
+	}
+	pb.OnModelBeforeCreate("items").Add(validate)
+	pb.OnModelBeforeUpdate("items").Add(validate)
+	// Children are deleted with their parent, which must not take personal
+	// items of other users along with a team item
+	pb.OnModelBeforeDelete("items").Add(func(e *core.ModelEvent) error {
+		record, ok := e.Model.(*models.Record)
+		if !ok {
+			return nil
+		}
+		n, err := countForeignDescendants(e.Dao, itemFromRecord(record))
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return errForeignChildren
+		}
+		return nil
+	})
+}
+
+// countForeignDescendants counts the children of the item at any depth,
+// which have a different owner than the item.
+func countForeignDescendants(dao *daos.Dao, item Item) (int, error) {
+	var n int
+	err := dao.DB().
+		NewQuery("WITH RECURSIVE tree(id, owner) AS (SELECT id, owner FROM items WHERE parent = {:id} UNION ALL SELECT i.id, i.owner FROM items i JOIN tree t ON i.parent = t.id) SELECT count(*) FROM tree WHERE owner != {:owner}").
+		Bind(dbx.Params{
+			"id":    item.ID,
+			"owner": item.Owner,
+		}).
+		Row(&n)
+	return n, err
+}
+
+// validateItem checks the fields of the item and whether its alias is free.
+func validateItem(dao *daos.Dao, item Item) error {
+	if err := item.Validate(); err != nil {
+		return err
+	}
+	if err := validateParent(dao, item); err != nil {
+		if _, ok := err.(validation.Error); ok {
+			return validation.Errors{"parent": err}
+		}
+		return err
+	}
+	err := validateAlias(dao, item)
+	if _, ok := err.(validation.Error); ok {
+		return validation.Errors{"alias": err}
+	}
+	return err
+}
+
+// validateParent keeps the children visible to everyone who can see their
+// parent, team items can't be nested under personal items.
+func validateParent(dao *daos.Dao, item Item) error {
+	if item.Parent == "" {
+		return nil
+	}
+	var owner string
+	err := dao.DB().
+		Select("owner").
+		From("items").
+		Where(dbx.HashExp{"id": item.Parent}).
+		Row(&owner)
+	if err != nil {
+		return err
+	}
+	if owner != "" && owner != item.Owner {
+		return validation.NewError("validation_invalid_parent", "must be a team item or an item of the same owner")
+	}
+	return nil
+}
+
+func validateAlias(dao *daos.Dao, item Item) error {
+	if item.Parent == "" {
+		for _, reserved := range RESERVED_ALIASES {
+			if item.Alias == reserved {
+				return validation.NewError("validation_reserved_alias", "is reserved")
+			}
+		}
+		var engines int
+		err := dao.DB().
+			Select("count(*)").
+			From("engines").
+			Where(dbx.HashExp{"alias": item.Alias}).
+			Row(&engines)
+		if err != nil {
+			return err
+		}
+		if engines > 0 {
+			return validation.NewError("validation_reserved_alias", "is used by a search engine")
+		}
+	}
+	var duplicates int
+	err := dao.DB().
+		Select("count(*)").
+		From("items").
+		Where(dbx.HashExp{"owner": item.Owner, "parent": item.Parent, "alias": item.Alias}).
+		AndWhere(dbx.Not(dbx.HashExp{"id": item.ID})).
+		Row(&duplicates)
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		return validation.NewError("validation_duplicate_alias", "is already used")
+	}
+	return nil
+}
+
+func itemFromRecord(record *models.Record) Item {
+	item := Item{
+		ID:     record.Id,
+		Parent: record.GetString("parent"),
+		Owner:  record.GetString("owner"),
+		Name:   record.GetString("name"),
+		Alias:  record.GetString("alias"),
+		URL:    record.GetString("url"),
+		Tags:   record.GetStringSlice("tags"),
+	}
+	// Invalid variants are left empty, the field only accepts JSON
+	record.UnmarshalJSONField("variants", &item.Variants)
+	return item
+}
+
+// visibleItems is an SQL condition on the items of the table alias, which
+// selects the team items and the personal items of the `{:user}` parameter.
+// Personal items shadow the team items with the same alias and parent.
+func visibleItems(table string) string {
+	return fmt.Sprintf(
+		"(%[1]s.owner = '' OR %[1]s.owner = {:user}) AND NOT (%[1]s.owner = '' AND EXISTS (SELECT 1 FROM items personal WHERE personal.owner = {:user} AND personal.owner != '' AND personal.parent = %[1]s.parent AND personal.alias = %[1]s.alias))",
+		table,
+	)
+}
+
+func visibleItemsExp(user string) dbx.Expression {
+	return dbx.NewExp(visibleItems("items"), dbx.Params{"user": user})
+}
+
+// getItemByID returns a team item or a personal item of the user, shadowed
+// items can still be edited.
+func getItemByID(pb *pocketbase.PocketBase, id string, user string) (Item, error) {
+	var item Item
+	err := pb.Dao().DB().
+		Select("id", "parent", "owner", "alias", "name", "url", "variants", "tags").
+		From("items").
+		Where(dbx.HashExp{"id": id}).
+		AndWhere(dbx.Or(dbx.HashExp{"owner": ""}, dbx.HashExp{"owner": user})).
+		One(&item)
+	if err != nil {
+		return item, err
+	}
+	return withParentPaths(pb, []Item{item})[0], nil
+}
+
+func updateItem(pb *pocketbase.PocketBase, item Item) (Item, error) {
+	record, err := pb.Dao().FindRecordById("items", item.ID)
+	if err != nil {
+		return item, err
+	}
+	setItemFields(record, item)
+	if err := pb.Dao().SaveRecord(record); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+func deleteItem(pb *pocketbase.PocketBase, id string, user string) error {
+	if _, err := getItemByID(pb, id, user); err != nil {
+		return err
+	}
+	record, err := pb.Dao().FindRecordById("items", id)
+	if err != nil {
+		return err
+	}
+	return pb.Dao().DeleteRecord(record)
+}
+
+type ItemsPage struct {
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+	TotalItems int    `json:"totalItems"`
+	TotalPages int    `json:"totalPages"`
+	Items      []Item `json:"items"`
+}
+
+// listItems returns a page of the items visible to the user ordered by their
+// aliases, optionally only the ones with the tag. Paths are only known after
+// the page is loaded, so children are not grouped under their parents.
+func listItems(pb *pocketbase.PocketBase, tag string, page int, perPage int, user string) (ItemsPage, error) {
+	result := ItemsPage{Page: page, PerPage: perPage, Items: []Item{}}
+	where := dbx.And(tagExp(tag), visibleItemsExp(user))
+	err := pb.Dao().DB().
+		Select("count(*)").
+		From("items").
+		Where(where).
+		Row(&result.TotalItems)
+	if err != nil {
+		return result, err
+	}
+	result.TotalPages = (result.TotalItems + perPage - 1) / perPage
+	err = pb.Dao().DB().
+		Select("id", "parent", "owner", "alias", "name", "url", "variants", "tags").
+		From("items").
+		Where(where).
+		OrderBy("alias", "created").
+		Offset(int64((page - 1) * perPage)).
+		Limit(int64(perPage)).
+		All(&result.Items)
+	if err != nil {
+		return result, err
+	}
+	result.Items = withParentPaths(pb, result.Items)
+	return result, nil
+}
+
+// getAllItems returns all items visible to the user ordered by their paths,
+// optionally only the ones with the tag. Without a user only the team items
+// are returned, which is what the command line tools work with.
+func getAllItems(pb *pocketbase.PocketBase, tag string, user string) ([]Item, error) {
+	items := make([]Item, 0)
+	err := pb.Dao().DB().
+		Select("id", "parent", "owner", "alias", "name", "url", "variants", "tags").
+		From("items").
+		Where(dbx.And(tagExp(tag), visibleItemsExp(user))).
+		OrderBy("alias", "created").
+		All(&items)
+	if err != nil {
+		return items, err
+	}
+	items = withParentPaths(pb, items)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Path < items[j].Path
+	})
+	return items, nil
+}
+
+func tagExp(tag string) dbx.Expression {
+	if tag == "" {
+		return dbx.NewExp("1 = 1")
+	}
+	return dbx.NewExp("EXISTS (SELECT 1 FROM json_each(items.tags) WHERE json_each.value = {:tag})", dbx.Params{"tag": tag})
+}
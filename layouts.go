@@ -0,0 +1,58 @@
+package main
+
+// KeyboardLayout maps the characters typed with one keyboard layout to the
+// characters on the same keys of another one, ex. `пр` to `gh` for a query
+// typed with the Russian layout active instead of the English one.
+type KeyboardLayout struct {
+	Name string
+	keys map[rune]rune
+}
+
+// NewKeyboardLayout creates a layout from two strings of characters, which
+// are on the same keys.
+func NewKeyboardLayout(name, from, to string) KeyboardLayout {
+	layout := KeyboardLayout{Name: name, keys: make(map[rune]rune)}
+	toRunes := []rune(to)
+	for i, r := range []rune(from) {
+		if i < len(toRunes) {
+			layout.keys[r] = toRunes[i]
+		}
+	}
+	return layout
+}
+
+// Reverse returns the layout which maps the characters back.
+func (l KeyboardLayout) Reverse(name string) KeyboardLayout {
+	reversed := KeyboardLayout{Name: name, keys: make(map[rune]rune, len(l.keys))}
+	for from, to := range l.keys {
+		reversed.keys[to] = from
+	}
+	return reversed
+}
+
+// Transpose maps every character of s, it returns false when none of them
+// belong to the layout.
+func (l KeyboardLayout) Transpose(s string) (string, bool) {
+	changed := false
+	result := []rune(s)
+	for i, r := range result {
+		if to, ok := l.keys[r]; ok {
+			result[i] = to
+			changed = true
+		}
+	}
+	return string(result), changed
+}
+
+var jcukenToQwerty = NewKeyboardLayout(
+	"ЙЦУКЕН → QWERTY",
+	"ёйцукенгшщзхъфывапролджэячсмитьбю.ЁЙЦУКЕНГШЩЗХЪФЫВАПРОЛДЖЭЯЧСМИТЬБЮ,",
+	"`qwertyuiop[]asdfghjkl;'zxcvbnm,./~QWERTYUIOP{}ASDFGHJKL:\"ZXCVBNM<>?",
+)
+
+// KeyboardLayouts are tried in order when the alias is not found, more
+// layouts can be added with NewKeyboardLayout.
+var KeyboardLayouts = []KeyboardLayout{
+	jcukenToQwerty,
+	jcukenToQwerty.Reverse("QWERTY → ЙЦУКЕН"),
+}
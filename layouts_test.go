@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestKeyboardLayoutTranspose(t *testing.T) {
+	reverse := jcukenToQwerty.Reverse("QWERTY → ЙЦУКЕН")
+	tests := []struct {
+		name   string
+		layout KeyboardLayout
+		s      string
+		want   string
+		ok     bool
+	}{
+		{name: "alias", layout: jcukenToQwerty, s: "пр", want: "gh", ok: true},
+		{name: "upper case", layout: jcukenToQwerty, s: "ПР", want: "GH", ok: true},
+		{name: "punctuation", layout: jcukenToQwerty, s: "хъжэбю.", want: "[];',./", ok: true},
+		{name: "shifted punctuation", layout: jcukenToQwerty, s: "ЖЭ,", want: ":\"?", ok: true},
+		{name: "mixed with latin", layout: jcukenToQwerty, s: "пр-2 fix", want: "gh-2 fix", ok: true},
+		{name: "mixed with latin punctuation", layout: jcukenToQwerty, s: "ghб.", want: "gh,/", ok: true},
+		{name: "nothing to change", layout: jcukenToQwerty, s: "gh-2", want: "gh-2", ok: false},
+		{name: "reverse", layout: reverse, s: "gh", want: "пр", ok: true},
+		{name: "reverse punctuation", layout: reverse, s: "[/", want: "х.", ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.layout.Transpose(tt.s)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Transpose(%q) = %q, %v, want %q, %v", tt.s, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
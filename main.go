@@ -17,6 +17,7 @@ import (
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
 	"github.com/pocketbase/pocketbase/models"
 	"github.com/pocketbase/pocketbase/plugins/migratecmd"
 	"github.com/pocketbase/pocketbase/tools/types"
@@ -30,7 +31,13 @@ func main() {
 	tmpls := web.NewTemplates(dev)
 	authMiddleware := &AuthMiddleware{pb}
 
+	registerItemHooks(pb)
+	registerDeviceHooks(pb)
+	registerSearchHooks(pb)
+
 	pb.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		setupSearchIndex(pb)
+
 		fsys, _ := fs.Sub(web.StaticFS, "static")
 
 		e.Router.GET("/static/*", apis.StaticDirectoryHandler(fsys, false))
@@ -38,28 +45,84 @@ func main() {
 		e.Router.GET("/", func(c echo.Context) error {
 			return tmpls.RenderEcho(c.Response().Writer, "index", nil, c)
 		}, authMiddleware.Process)
+		registerItemForms(e.Router, pb, tmpls, authMiddleware)
+
+		e.Router.GET("/devices", func(c echo.Context) error {
+			data := map[string]any{
+				"Devices": getDevicesUsage(pb, userDevicesExp(getUserID(c))),
+				"Current": getDeviceID(c),
+			}
+			return tmpls.RenderEcho(c.Response().Writer, "devices", data, c)
+		}, authMiddleware.Process)
 
-		e.Router.GET("/new", func(c echo.Context) error {
-			return tmpls.RenderEcho(c.Response().Writer, "new", c.Request().URL.Query().Get("alias"), c)
+		e.Router.POST("/devices/pairing", func(c echo.Context) error {
+			name := strings.TrimSpace(c.FormValue("name"))
+			if name == "" {
+				return c.String(http.StatusOK, "The name of the device is required")
+			}
+			code, expires, err := createPairingCode(pb, name, getUserID(c))
+			if err != nil {
+				return c.String(http.StatusOK, err.Error())
+			}
+			data := map[string]any{
+				"Name":     name,
+				"Code":     code,
+				"Expires":  expires.Time().Local().Format("15:04"),
+				"LoginURL": getBaseURL(pb) + "/login?code=" + url.QueryEscape(code),
+			}
+			return tmpls.RenderEcho(c.Response().Writer, "pairing", data, c)
 		}, authMiddleware.Process)
 
-		e.Router.POST("/items", func(c echo.Context) error {
-			var newItem Item
-			if err := c.Bind(&newItem); err != nil {
+		e.Router.POST("/devices/:id/revoke", func(c echo.Context) error {
+			record, err := pb.Dao().FindRecordById("devices", c.PathParam("id"))
+			if err != nil || record.GetString("user") != getUserID(c) {
+				return apis.NewNotFoundError("", err)
+			}
+			if _, err := revokeDevice(pb.Dao(), record.Id); err != nil {
+				return apis.NewNotFoundError("", err)
+			}
+			c.Response().Header().Set("HX-Refresh", "true")
+			return c.String(http.StatusOK, "ok")
+		}, authMiddleware.Process)
+
+		e.Router.GET("/import", func(c echo.Context) error {
+			return tmpls.RenderEcho(c.Response().Writer, "import", nil, c)
+		}, authMiddleware.Process)
+
+		e.Router.POST("/import/bookmarks", func(c echo.Context) error {
+			header, err := c.FormFile("file")
+			if err != nil {
 				return c.String(http.StatusBadRequest, err.Error())
 			}
-			err := createItem(pb, newItem)
+			file, err := header.Open()
 			if err != nil {
 				return c.String(http.StatusBadRequest, err.Error())
 			}
-			c.Response().Header().Set("HX-Redirect", "/")
-			return c.String(http.StatusOK, "ok")
+			defer file.Close()
+			dryRun := c.FormValue("dry_run") != ""
+			report, err := importBookmarks(pb, file, dryRun)
+			if err != nil {
+				return c.String(http.StatusBadRequest, err.Error())
+			}
+			var output bytes.Buffer
+			report.Print(&output, dryRun)
+			return tmpls.RenderEcho(c.Response().Writer, "imported", output.String(), c)
+		}, authMiddleware.Process)
+
+		e.Router.GET("/export/bookmarks", func(c echo.Context) error {
+			items, err := getAllItems(pb, "", getUserID(c))
+			if err != nil {
+				return c.String(http.StatusInternalServerError, err.Error())
+			}
+			c.Response().Header().Set("Content-Type", "text/html; charset=utf-8")
+			c.Response().Header().Set("Content-Disposition", `attachment; filename="links.html"`)
+			return writeBookmarks(c.Response().Writer, items)
 		}, authMiddleware.Process)
 
 		e.Router.GET("/items", func(c echo.Context) error {
 			q := c.Request().URL.Query().Get("q")
 			var ctx ItemsContext
-			itemsResult := getItems(pb, q)
+			itemsResult := getItems(pb, q, getDeviceID(c), getUserID(c))
 			ctx.Items = itemsResult.Items
 			switch itemsResult.State {
 			case NEW_ITEM:
@@ -67,12 +130,23 @@ func main() {
 				return tmpls.RenderEcho(c.Response().Writer, "items", ctx, c)
 			case ARGS_MODE:
 				ctx.Expansion = itemsResult.Expansion
+				ctx.Engine = itemsResult.Engine
+				ctx.Fallback = itemsResult.Fallback
+				ctx.OtherEngines = itemsResult.OtherEngines
+				ctx.Correction = itemsResult.Correction
 				return tmpls.RenderEcho(c.Response().Writer, "items", ctx, c)
-			case GOOGLE_MODE:
+			case DID_YOU_MEAN:
+				ctx.DidYouMean = true
 				ctx.Expansion = itemsResult.Expansion
-				ctx.IsGoogle = true
+				ctx.Engine = itemsResult.Engine
+				ctx.Fallback = itemsResult.Fallback
+				ctx.OtherEngines = itemsResult.OtherEngines
+				if itemsResult.Expansion.URL == "" {
+					ctx.New = itemsResult.FirstQ
+				}
 				return tmpls.RenderEcho(c.Response().Writer, "items", ctx, c)
 			default:
+				ctx.Correction = itemsResult.Correction
 				return tmpls.RenderEcho(c.Response().Writer, "items", ctx, c)
 			}
 		}, authMiddleware.Process)
@@ -82,6 +156,7 @@ func main() {
 			pb.Dao().DB().
 				Select("id", "alias", "args", "created").
 				From("logs").
+				Where(userLogsExp(getUserID(c))).
 				Limit(30).
 				OrderBy("created DESC").
 				All(&logs)
@@ -97,8 +172,8 @@ func main() {
 		}, authMiddleware.Process)
 
 		e.Router.GET("/stats", func(c echo.Context) error {
-			topN, _ := getTopAliases(pb, 10)
-			lowN, _ := getTopAliases(pb, -10)
+			topN, _ := getTopAliases(pb, 10, getUserID(c))
+			lowN, _ := getTopAliases(pb, -10, getUserID(c))
 			result := make(map[string]interface{})
 			result["topn"] = topN
 			result["lown"] = lowN
@@ -108,16 +183,14 @@ func main() {
 		e.Router.GET("/expand/html", func(c echo.Context) error {
 			q := c.QueryParam("q")
 
-			itemsResult := getItems(pb, q)
+			itemsResult := getItems(pb, q, getDeviceID(c), getUserID(c))
+			if itemsResult.State == DID_YOU_MEAN && itemsResult.Expansion.URL == "" {
+				itemsResult.State = NEW_ITEM
+			}
 			switch itemsResult.State {
 			case NEW_ITEM:
 				c.Response().Header().Set("HX-Redirect", fmt.Sprintf("/new?alias=%s", itemsResult.FirstQ))
 				return c.String(http.StatusOK, "ok")
-			case GOOGLE_MODE:
-				// This is a special shortcut
-				createLog(pb, itemsResult.Expansion.Alias, itemsResult.Expansion.Args, c.Get(DEVICE_ID_CONTEXT_KEY).(string))
-				c.Response().Header().Set("HX-Redirect", itemsResult.Expansion.URL)
-				return c.String(http.StatusOK, "ok")
 			default:
 				createLog(pb, itemsResult.Expansion.Alias, itemsResult.Expansion.Args, c.Get(DEVICE_ID_CONTEXT_KEY).(string))
 				c.Response().Header().Set("HX-Redirect", itemsResult.Expansion.URL)
@@ -127,20 +200,42 @@ func main() {
 
 		e.Router.GET("/api/items", func(c echo.Context) error {
 			q := c.Request().URL.Query().Get("q")
-			itemsResult := getItems(pb, q)
+			itemsResult := getItems(pb, q, getDeviceID(c), getUserID(c))
 			return c.JSON(http.StatusOK, itemsResult.Items)
-		})
+		}, authMiddleware.Process)
+
+		registerItemsAPI(e.Router, pb, authMiddleware)
+
+		e.Router.GET("/api/search", func(c echo.Context) error {
+			q := c.QueryParam("q")
+			items := searchItems(pb, q, SEARCH_LIMIT, getUserID(c))
+			if c.QueryParam("format") == "" {
+				return c.JSON(http.StatusOK, items)
+			}
+			feedURL := fmt.Sprintf("%s/api/search?q=%s", getBaseURL(pb), url.QueryEscape(q))
+			return renderFeed(c, newFeed(fmt.Sprintf("Links matching %q", q), feedURL, getFeedItems(pb, items)))
+		}, authMiddleware.Process)
+
+		e.Router.GET("/feeds/new-items", func(c echo.Context) error {
+			feedURL := getBaseURL(pb) + "/feeds/new-items"
+			return renderFeed(c, newFeed("New links", feedURL, getRecentItems(pb, FEED_LIMIT, getUserID(c))))
+		}, authMiddleware.Process)
 
 		e.Router.GET("/api/expand", func(c echo.Context) error {
 			q := c.QueryParam("q")
-			itemsResult := getItems(pb, q)
+			itemsResult := getItems(pb, q, getDeviceID(c), getUserID(c))
+			if correction := itemsResult.Correction; correction != nil {
+				// Only the confident corrections get here, so it's safe to follow them
+				c.Response().Header().Set(CORRECTION_HEADER, correction.String())
+				createLog(pb, correction.Expansion.Alias, correction.Expansion.Args, c.Get(DEVICE_ID_CONTEXT_KEY).(string))
+				return c.Redirect(http.StatusTemporaryRedirect, correction.Expansion.URL)
+			}
+			if itemsResult.State == DID_YOU_MEAN && itemsResult.Expansion.URL == "" {
+				itemsResult.State = NEW_ITEM
+			}
 			switch itemsResult.State {
 			case NEW_ITEM:
 				return c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("/new?alias=%s", itemsResult.FirstQ))
-			case GOOGLE_MODE:
-				// This is a special shortcut
-				createLog(pb, itemsResult.Expansion.Alias, itemsResult.Expansion.Args, c.Get(DEVICE_ID_CONTEXT_KEY).(string))
-				return c.Redirect(http.StatusTemporaryRedirect, itemsResult.Expansion.URL)
 			default:
 				createLog(pb, itemsResult.Expansion.Alias, itemsResult.Expansion.Args, c.Get(DEVICE_ID_CONTEXT_KEY).(string))
 				return c.Redirect(http.StatusTemporaryRedirect, itemsResult.Expansion.URL)
@@ -148,54 +243,75 @@ func main() {
 		}, authMiddleware.Process)
 
 		e.Router.GET("/api/opensearch", func(c echo.Context) error {
-			// TODO: possibly parse `format` GET-parameter and output differently, i.e. in XML
 			q := c.QueryParam("q")
-			qParts := strings.Split(q, " ")
-			itemsResult := getItems(pb, q)
-			suggestions := make([]string, len(itemsResult.Items))
-			for i := 0; i < len(itemsResult.Items); i++ {
-				expansion := expand(itemsResult.Items[i], q)
-				suggestions[i] = fmt.Sprintf("%s %s %s", itemsResult.Items[i].Alias, qParts[:1], expansion.URL)
-			}
-			result := []interface{}{
-				q,
-				suggestions,
-				// This doesn't work, dunno why is it in a specification
-				// https://github.com/dewitt/opensearch/blob/master/mediawiki/Specifications/OpenSearch/Extensions/Suggestions/1.1/Draft%201.wiki
-				// []string{"description"},
-				// []string{"https://google.com/?q=asdf"},
-			}
-			return c.JSON(http.StatusOK, result)
-		})
+			suggestions := newSuggestions(q, getItems(pb, q, getDeviceID(c), getUserID(c)))
+			if c.QueryParam("format") == "xml" {
+				data, err := suggestions.XML()
+				if err != nil {
+					return err
+				}
+				return c.Blob(http.StatusOK, "application/x-suggestions+xml", data)
+			}
+			return c.JSON(http.StatusOK, suggestions)
+		}, authMiddleware.Process)
+
+		e.Router.GET("/api/policies/:browser", func(c echo.Context) error {
+			keyword := c.QueryParam("keyword")
+			if keyword == "" {
+				keyword = DEFAULT_POLICY_KEYWORD
+			}
+			policy, err := getPolicy(pb, c.PathParam("browser"), c.QueryParam("tag"), keyword, c.QueryParam("device_token"))
+			if err != nil {
+				return apis.NewBadRequestError(err.Error(), nil)
+			}
+			return c.JSON(http.StatusOK, policy)
+		}, authMiddleware.Process)
 
 		e.Router.GET("/opensearch.xml", func(c echo.Context) error {
 			// https://github.com/dewitt/opensearch/blob/master/opensearch-1-1-draft-6.md
-			// TODO:
-			// 	- add more response formats
-			// 	<Url type="application/atom+xml" template="{{ .BaseURL }}/?q={searchTerms}&amp;format=atom"/>
-			// 	<Url type="application/rss+xml" template="{{ .BaseURL }}/?q={searchTerms}&amp;pw={startPage?}&amp;format=rss"/>
-
-			appUrl := pb.Settings().Meta.AppUrl
+			// Not using AuthMiddleware, because Firefox can't download the search engine
+			// definition otherwise. A signed in browser gets the token in the search URLs,
+			// so that the search engine keeps working without the cookie.
+			data := map[string]string{"BaseURL": getBaseURL(pb)}
+			if token, ok := authMiddleware.authenticateRequest(c); ok {
+				data["Token"] = url.QueryEscape(token)
+			}
 			var output bytes.Buffer
-			err := tmpls.Execute(&output, "opensearch", map[string]string{"BaseURL": appUrl})
+			err := tmpls.Execute(&output, "opensearch", data)
 			if err != nil {
 				return err
 			}
 			c.Response().Header().Set("Content-Type", "application/opensearchdescription+xml")
 			return c.XMLBlob(http.StatusOK, output.Bytes())
-			// not using AuthMiddleware, because Firefox can't download the search engine definition otherwise.
 		})
 
 		e.Router.GET("/login", func(c echo.Context) error {
-			return tmpls.RenderEcho(c.Response().Writer, "login", nil, c)
+			return tmpls.RenderEcho(c.Response().Writer, "login", map[string]string{"Code": c.QueryParam("code")}, c)
 		})
 
+		// The token field also accepts pairing codes, which are exchanged for
+		// a new device and its token
 		e.Router.POST("/login", func(c echo.Context) error {
 			c.Request().ParseForm()
+			token := strings.TrimSpace(c.FormValue("token"))
+			device, ok := getDeviceByToken(pb, token)
+			if !ok {
+				device, token, ok = pairDevice(pb, token)
+			}
+			if !ok {
+				return c.String(http.StatusOK, "Invalid token or pairing code")
+			}
 			cookie := new(http.Cookie)
 			cookie.Name = COOKIE_NAME
-			cookie.Value = c.FormValue("token")
+			cookie.Value = token
+			cookie.Path = "/"
 			cookie.Expires = time.Now().Add(60 * 24 * time.Hour)
+			if expires := device.Expires.Time(); !device.Expires.IsZero() && expires.Before(cookie.Expires) {
+				cookie.Expires = expires
+			}
+			cookie.HttpOnly = true
+			cookie.Secure = c.Scheme() == "https"
+			cookie.SameSite = http.SameSiteLaxMode
 			c.SetCookie(cookie)
 			c.Response().Header().Set("HX-Redirect", "/")
 			return c.String(http.StatusOK, "ok")
@@ -204,6 +320,13 @@ func main() {
 		return nil
 	})
 
+	pb.RootCmd.AddCommand(newImportCommand(pb))
+	pb.RootCmd.AddCommand(newExportCommand(pb))
+	pb.RootCmd.AddCommand(newPlanCommand(pb))
+	pb.RootCmd.AddCommand(newApplyCommand(pb))
+	pb.RootCmd.AddCommand(newPolicyCommand(pb))
+	pb.RootCmd.AddCommand(newDeviceCommand(pb))
+
 	isGoRun := strings.HasPrefix(os.Args[0], os.TempDir())
 
 	migratecmd.MustRegister(pb, pb.RootCmd, migratecmd.Config{
@@ -219,14 +342,24 @@ func main() {
 
 const (
 	DEVICE_ID_CONTEXT_KEY = "device_id"
+	USER_ID_CONTEXT_KEY   = "user_id"
 	COOKIE_NAME           = "links_auth"
+	CORRECTION_HEADER     = "X-Links-Correction"
 )
 
+// Item is personal when it has an owner, otherwise it is shared by the team.
 type Item struct {
-	Name  string   `db:"name" form:"name" json:"name"`
-	Alias string   `db:"alias" form:"alias" json:"alias"`
-	URL   string   `db:"url" form:"url" json:"url"`
-	Tags  []string `form:"tags" json:"tags"`
+	ID       string                  `db:"id" json:"id"`
+	Parent   string                  `db:"parent" form:"parent" json:"parent"`
+	Owner    string                  `db:"owner" json:"owner"`
+	Name     string                  `db:"name" form:"name" json:"name"`
+	Alias    string                  `db:"alias" form:"alias" json:"alias"`
+	URL      string                  `db:"url" form:"url" json:"url"`
+	Variants Variants                `db:"variants" json:"variants"`
+	Tags     types.JsonArray[string] `db:"tags" form:"tags" json:"tags"`
+	// Path is the full alias of the item including the aliases of its
+	// parents, ex. `gh pr`.
+	Path string `db:"-" json:"path"`
 }
 
 type Expansion struct {
@@ -234,13 +367,52 @@ type Expansion struct {
 	Args      []string
 	URL       string
 	ExpandURL string
+	// Variant is the arity key of the URL variant used for the expansion,
+	// it is empty when the item URL was used.
+	Variant string
 }
 
 type ItemsContext struct {
 	New       string
 	Expansion Expansion
 	Items     []Item
-	IsGoogle  bool
+	// Engine is the name of the fallback search engine used for the query
+	Engine       string
+	Fallback     bool
+	OtherEngines []EngineExpansion
+	DidYouMean   bool
+	Correction   *Correction
+}
+
+// ItemForm is used both for creating and editing items. New items can be
+// personal when the device has a user.
+type ItemForm struct {
+	Item     Item
+	Tags     string
+	Variants string
+	User     string
+}
+
+func newItemForm(item Item, user string) ItemForm {
+	return ItemForm{
+		Item:     item,
+		Tags:     strings.Join(item.Tags, ", "),
+		Variants: formatVariants(item.Variants),
+		User:     user,
+	}
+}
+
+// splitTags splits comma separated tags from the form.
+func splitTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		for _, part := range strings.Split(tag, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
 }
 
 type Log struct {
@@ -256,62 +428,110 @@ type LogContext struct {
 	CreatedAt string
 }
 
-func expand(item Item, q string) Expansion {
-	url := item.URL
-	substCount := strings.Count(url, "%s")
-	qParts := strings.SplitN(q, " ", substCount+1)
-	args := make([]string, len(qParts)-1)
-	// First element is search prefix, we don't need that
-	for i := 1; i < len(qParts); i++ {
-		if strings.Contains(url, "%s") {
-			args[i-1] = qParts[i]
-			url = strings.Replace(url, "%s", qParts[i], 1)
-		}
-	}
+func expand(item Item, args []string) Expansion {
+	url, variant := item.variantFor(len(args))
 	return Expansion{
-		Alias: item.Alias,
-		Args:  args,
-		URL:   url,
+		Alias:   item.Path,
+		Args:    args,
+		URL:     compileTemplate(url).Expand(args),
+		Variant: variant,
 	}
 }
 
-func getItemsByPrefix(pb *pocketbase.PocketBase, prefix string) []Item {
+// getItemsByPrefix looks up the children of the parent item by their alias
+// prefix, top level items have an empty parent. Items with the same match
+// are ordered by their frecency scores of the ranking device. Personal items
+// of the user shadow the team items with the same alias.
+func getItemsByPrefix(pb *pocketbase.PocketBase, parent Item, prefix string, rankingDevice string, user string) []Item {
+	path := ""
+	if parent.Path != "" {
+		path = parent.Path + " "
+	}
 	items := make([]Item, 0)
 	pb.Dao().DB().
-		NewQuery("SELECT alias, name, url FROM items WHERE alias LIKE {:like} ORDER BY (CASE WHEN alias = {:prefix} THEN 1 WHEN alias LIKE {:like} THEN 2 ELSE 3 END), alias, created ASC LIMIT 10").
+		NewQuery("SELECT i.id, i.parent, i.owner, i.alias, i.name, i.url, i.variants, i.tags FROM items i LEFT JOIN frecency f ON f.alias = ({:path} || i.alias) AND f.device = {:device} WHERE i.parent = {:parent} AND i.alias LIKE {:like} AND " + visibleItems("i") + " ORDER BY (CASE WHEN i.alias = {:prefix} THEN 1 WHEN i.alias LIKE {:like} THEN 2 ELSE 3 END), COALESCE(f.score, 0) DESC, i.alias, i.created ASC LIMIT {:limit}").
 		Bind(dbx.Params{
+			"parent": parent.ID,
+			"path":   path,
+			"device": rankingDevice,
 			"prefix": prefix,
 			"like":   prefix + "%",
+			"limit":  SEARCH_LIMIT,
+			"user":   user,
 		}).
 		All(&items)
-	return items
+	return withPaths(items, parent)
 }
 
-func getItemsByExactMatch(pb *pocketbase.PocketBase, alias string) []Item {
+func getItemsByExactMatch(pb *pocketbase.PocketBase, parent Item, alias string, user string) []Item {
 	items := make([]Item, 0)
 	pb.Dao().DB().
-		NewQuery("SELECT alias, name, url FROM items WHERE alias = {:alias}").
+		NewQuery("SELECT i.id, i.parent, i.owner, i.alias, i.name, i.url, i.variants, i.tags FROM items i WHERE i.parent = {:parent} AND i.alias = {:alias} AND " + visibleItems("i")).
 		Bind(dbx.Params{
-			"alias": alias,
+			"parent": parent.ID,
+			"alias":  alias,
+			"user":   user,
 		}).
 		All(&items)
+	return withPaths(items, parent)
+}
+
+func withPaths(items []Item, parent Item) []Item {
+	for i := range items {
+		items[i].Path = items[i].Alias
+		if parent.Path != "" {
+			items[i].Path = parent.Path + " " + items[i].Alias
+		}
+	}
 	return items
 }
 
-func createItem(pb *pocketbase.PocketBase, item Item) error {
-	collection, err := pb.Dao().FindCollectionByNameOrId("items")
+// resolveExpansion expands the item or one of its sub-commands.
+func resolveExpansion(pb *pocketbase.PocketBase, item Item, args []string, user string) (Item, Expansion) {
+	item, args = resolveChildren(pb, item, args, user)
+	return item, expand(item, args)
+}
+
+// resolveChildren descends from the item into its children while the tokens
+// match their aliases, it returns the deepest item and the remaining tokens.
+func resolveChildren(pb *pocketbase.PocketBase, item Item, tokens []string, user string) (Item, []string) {
+	for len(tokens) > 0 {
+		children := getItemsByExactMatch(pb, item, tokens[0], user)
+		if len(children) == 0 {
+			break
+		}
+		item = children[0]
+		tokens = tokens[1:]
+	}
+	return item, tokens
+}
+
+func createItem(pb *pocketbase.PocketBase, item Item) (Item, error) {
+	return saveNewItem(pb.Dao(), item)
+}
+
+func saveNewItem(dao *daos.Dao, item Item) (Item, error) {
+	collection, err := dao.FindCollectionByNameOrId("items")
 	if err != nil {
-		return err
+		return item, err
 	}
 	record := models.NewRecord(collection)
+	setItemFields(record, item)
+	if err := dao.SaveRecord(record); err != nil {
+		return item, err
+	}
+	item.ID = record.Id
+	return item, nil
+}
+
+func setItemFields(record *models.Record, item Item) {
 	record.Set("name", item.Name)
 	record.Set("alias", item.Alias)
+	record.Set("parent", item.Parent)
+	record.Set("owner", item.Owner)
 	record.Set("url", item.URL)
+	record.Set("variants", item.Variants)
 	record.Set("tags", item.Tags)
-	if err := pb.Dao().SaveRecord(record); err != nil {
-		return err
-	}
-	return nil
 }
 
 func createLog(pb *pocketbase.PocketBase, alias string, args []string, deviceId string) error {
@@ -319,14 +539,16 @@ func createLog(pb *pocketbase.PocketBase, alias string, args []string, deviceId
 	if err != nil {
 		return err
 	}
-	record := models.NewRecord(collection)
-	record.Set("alias", alias)
-	record.Set("args", args)
-	record.Set("device", deviceId)
-	if err := pb.Dao().SaveRecord(record); err != nil {
-		return err
-	}
-	return nil
+	return pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		record := models.NewRecord(collection)
+		record.Set("alias", alias)
+		record.Set("args", args)
+		record.Set("device", deviceId)
+		if err := txDao.SaveRecord(record); err != nil {
+			return err
+		}
+		return bumpFrecency(txDao, alias, deviceId, time.Now())
+	})
 }
 
 type TopAlias struct {
@@ -334,7 +556,16 @@ type TopAlias struct {
 	Count int64  `db:"count"`
 }
 
-func getTopAliases(pb *pocketbase.PocketBase, limit int64) ([]TopAlias, error) {
+// userLogsExp selects the logs of the devices of the user, the devices without
+// a user also see the logs from before the devices were added.
+func userLogsExp(user string) dbx.Expression {
+	return dbx.NewExp(
+		"device IN (SELECT id FROM devices WHERE user = {:user}) OR ({:user} = '' AND device = '')",
+		dbx.Params{"user": user},
+	)
+}
+
+func getTopAliases(pb *pocketbase.PocketBase, limit int64, user string) ([]TopAlias, error) {
 	order := "DESC"
 	if limit < 0 {
 		limit = -limit
@@ -344,6 +575,7 @@ func getTopAliases(pb *pocketbase.PocketBase, limit int64) ([]TopAlias, error) {
 	pb.Dao().DB().
 		Select("alias", "count(*) as count").
 		From("logs").
+		Where(userLogsExp(user)).
 		GroupBy("alias").
 		OrderBy("count(*)" + order).
 		AndOrderBy("created ASC").
@@ -359,7 +591,7 @@ const (
 	MULTIPLE_ITEMS            = 1
 	NEW_ITEM                  = 2
 	ARGS_MODE                 = 3
-	GOOGLE_MODE               = 4
+	DID_YOU_MEAN              = 4
 )
 
 type ItemsResult struct {
@@ -367,90 +599,223 @@ type ItemsResult struct {
 	Items     []Item
 	Expansion Expansion
 	FirstQ    string
+	Engine    string
+	// Fallback is set when the engine was used because nothing matched,
+	// rather than picked by its alias
+	Fallback bool
+	// OtherEngines are the rest of the fallback engines in their order
+	OtherEngines []EngineExpansion
+	// Correction is set when the alias is confidently a typo of another one
+	// and it can be followed
+	Correction *Correction
 }
 
-func getItems(pb *pocketbase.PocketBase, q string) ItemsResult {
+func getItems(pb *pocketbase.PocketBase, q string, deviceId string, user string) ItemsResult {
 	appURL := pb.Settings().Meta.AppUrl
-	// q is a space separated alias with parameters, which has to be split into
-	// certain number of parts, which are replaced in %s in the URL
+	// q is an alias with parameters, which are substituted into the URL template
 	// For example, q can be `g test`. `g` is an alias and `test` is a parameter.
-	qParts := strings.Split(q, " ")
+	query := parseQuery(q)
+	rankingDevice := getRankingDevice(pb, deviceId)
 	result := ItemsResult{
 		State:     UNKNOWN,
 		Expansion: Expansion{},
 		Items:     []Item{},
-		FirstQ:    qParts[0],
+		FirstQ:    query.Alias(),
 	}
 
-	var items []Item
+	items := lookupItems(pb, query, rankingDevice, user)
 
-	if len(qParts) > 1 {
-		items = getItemsByExactMatch(pb, qParts[0])
-	} else {
-		// Fisrt element of the query is ~~almost~~ always an alias prefix
-		items = getItemsByPrefix(pb, qParts[0])
+	if len(items) == 0 && query.Complete {
+		// Search engines are regular items, which can be used by their alias
+		if engine, ok := getEngineByAlias(pb, query.Alias()); ok {
+			result.State = ARGS_MODE
+			result.Engine = engine.Name
+			result.Expansion = expand(engine.Item(), query.Args())
+			result.Expansion.ExpandURL = fmt.Sprintf("%s/api/expand?q=%s", appURL, url.QueryEscape(q))
+			return result
+		}
 	}
 
+	// The alias might have been typed with a wrong keyboard layout
+	var correction *Correction
 	if len(items) == 0 {
-		if len(qParts) > 1 {
-			result.State = GOOGLE_MODE
-			googleQ := strings.Join(qParts, " ")
-			if qParts[0] == "g" {
-				googleQ = strings.Join(qParts[1:], " ")
-			}
-			googleQ = url.QueryEscape(googleQ)
-			result.Expansion = Expansion{
-				Alias:     "g",
-				Args:      qParts[1:],
-				URL:       "https://google.com/search?q=" + googleQ,
-				ExpandURL: fmt.Sprintf("%s/api/expand?q=%s", appURL, googleQ),
+		for _, layout := range KeyboardLayouts {
+			alias, ok := layout.Transpose(query.Alias())
+			if !ok {
+				continue
+			}
+			corrected := query.WithAlias(alias)
+			if items = lookupItems(pb, corrected, rankingDevice, user); len(items) > 0 {
+				correction = &Correction{From: query.Alias(), To: alias, Reason: layout.Name}
+				query = corrected
+				break
 			}
-			return result
 		}
+	}
+
+	if len(items) == 0 {
 		result.State = NEW_ITEM
+		similar, confident := getSimilarItems(pb, query.Alias(), user)
+		if len(similar) > 0 {
+			result.State = DID_YOU_MEAN
+			result.Items = similar
+		}
+		if confident && followsTypo(query) {
+			item, expansion := resolveExpansion(pb, similar[0], query.Args(), user)
+			result.Correction = &Correction{
+				From:      query.Alias(),
+				To:        item.Path,
+				Reason:    "typo",
+				Expansion: expansion,
+			}
+		}
+		if !query.Complete {
+			result.Items = mergeItems(result.Items, searchItems(pb, query.Alias(), SEARCH_LIMIT, user), SEARCH_LIMIT)
+			return result
+		}
+		// Otherwise the whole query goes to the fallback search engine
+		args := query.SearchTerms()
+		if engines := getFallbackEngines(pb, deviceId); len(engines) > 0 {
+			if result.State == NEW_ITEM {
+				result.State = ARGS_MODE
+			}
+			result.Engine = engines[0].Name
+			result.Fallback = true
+			result.Expansion = expand(engines[0].Item(), args)
+			result.Expansion.ExpandURL = fmt.Sprintf("%s/api/expand?q=%s", appURL, url.QueryEscape(q))
+			for _, engine := range engines[1:] {
+				result.OtherEngines = append(result.OtherEngines, EngineExpansion{
+					Name:      engine.Name,
+					Expansion: expand(engine.Item(), args),
+				})
+			}
+		}
 		return result
 	}
 
 	result.Items = items
 	result.State = MULTIPLE_ITEMS
-	result.Expansion = expand(items[0], q)
+	result.Expansion = expand(items[0], query.Args())
+	if !query.Complete {
+		result.Items = mergeItems(items, searchItems(pb, query.Alias(), SEARCH_LIMIT, user), SEARCH_LIMIT)
+	}
 
-	if len(qParts) > 1 && len(items) > 0 {
+	if query.Complete {
+		// The rest of the tokens can be sub-commands, ex. `gh pr 12`
+		item, expansion := resolveExpansion(pb, items[0], query.Args(), user)
 		result.State = ARGS_MODE
-		result.Expansion = expand(items[0], q)
-		result.Expansion.ExpandURL = fmt.Sprintf("%s/api/expand?q=%s", appURL, q)
-		return result
+		result.Items = []Item{item}
+		result.Expansion = expansion
+		result.Expansion.ExpandURL = fmt.Sprintf("%s/api/expand?q=%s", appURL, url.QueryEscape(q))
+		// Suggest sub-commands while the next token is being typed
+		args := expansion.Args
+		prefix := ""
+		if len(args) == 1 && !query.Trailing {
+			prefix = args[0]
+		}
+		if len(args) == 0 || prefix != "" {
+			if children := getItemsByPrefix(pb, item, prefix, rankingDevice, user); len(children) > 0 {
+				result.Items = children
+			}
+		}
 	}
 
+	if correction != nil {
+		correction.Expansion = result.Expansion
+		result.Correction = correction
+	}
 	return result
 }
 
+// lookupItems finds the items by the exact alias when it is complete,
+// otherwise by its prefix. Full-text matches are only listed after these,
+// they are never expanded.
+func lookupItems(pb *pocketbase.PocketBase, query Query, rankingDevice string, user string) []Item {
+	if query.Complete {
+		return getItemsByExactMatch(pb, Item{}, query.Alias(), user)
+	}
+	// Fisrt element of the query is ~~almost~~ always an alias prefix
+	return getItemsByPrefix(pb, Item{}, query.Alias(), rankingDevice, user)
+}
+
 type AuthMiddleware struct {
 	pb *pocketbase.PocketBase
 }
 
-type Device struct {
-	ID    string `db:"id"`
-	Token string `db:"token"`
+func getDeviceID(c echo.Context) string {
+	deviceId, _ := c.Get(DEVICE_ID_CONTEXT_KEY).(string)
+	return deviceId
+}
+
+// getUserID returns the user of the device, devices without a user only
+// have access to the team items.
+func getUserID(c echo.Context) string {
+	userId, _ := c.Get(USER_ID_CONTEXT_KEY).(string)
+	return userId
 }
 
+// Process authenticates the device by its token, which can be sent in the
+// cookie, in the `Authorization: Bearer` header or in the `token` query
+// parameter for the search engines and feed readers, which can't send
+// either of them. API routes respond with 401, the pages are left empty.
 func (m *AuthMiddleware) Process(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		cookie, err := c.Cookie(COOKIE_NAME)
-		if err != nil {
-			return c.String(http.StatusOK, "")
-		}
-		devices := []Device{}
-		m.pb.Dao().DB().
-			NewQuery("SELECT id, token FROM devices WHERE token = {:token}").
-			Bind(dbx.Params{
-				"token": cookie.Value,
-			}).
-			All(&devices)
-		if len(devices) != 1 {
+		if _, ok := m.authenticateRequest(c); !ok {
+			if isAPIRequest(c) {
+				return apis.NewUnauthorizedError("The request requires a valid device token.", nil)
+			}
 			return c.String(http.StatusOK, "")
 		}
-		c.Set(DEVICE_ID_CONTEXT_KEY, devices[0].ID)
 		return next(c)
 	}
 }
+
+// getTokens returns the tokens of the request in the order they are tried:
+// the bearer token, the cookie and the `token` query parameter.
+func getTokens(c echo.Context) []string {
+	tokens := make([]string, 0, 3)
+	if token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer "); ok {
+		tokens = append(tokens, strings.TrimSpace(token))
+	}
+	if cookie, err := c.Cookie(COOKIE_NAME); err == nil && cookie.Value != "" {
+		tokens = append(tokens, cookie.Value)
+	}
+	if token := c.QueryParam("token"); token != "" {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// authenticateRequest returns the first token of the request which
+// authenticates, so that a stale cookie doesn't hide a valid token in the
+// URL.
+func (m *AuthMiddleware) authenticateRequest(c echo.Context) (string, bool) {
+	for _, token := range getTokens(c) {
+		if m.authenticate(c, token) {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+func isAPIRequest(c echo.Context) bool {
+	path := c.Request().URL.Path
+	return strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/feeds/")
+}
+
+// authenticate sets the device of the token in the context.
+func (m *AuthMiddleware) authenticate(c echo.Context, token string) bool {
+	if token == "" {
+		return false
+	}
+	device, ok := getDeviceByToken(m.pb, token)
+	if !ok {
+		return false
+	}
+	if err := touchDevice(m.pb, device); err != nil {
+		log.Printf("failed to update the last use of the device %s: %v", device.ID, err)
+	}
+	c.Set(DEVICE_ID_CONTEXT_KEY, device.ID)
+	c.Set(USER_ID_CONTEXT_KEY, device.User)
+	return true
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is the declarative list of items kept in YAML or JSON. Nested
+// items use their full path as the alias, ex. `gh pr`, and their parents
+// must be in the manifest or in the database.
+type Manifest struct {
+	Items []ManifestItem `yaml:"items" json:"items"`
+}
+
+type ManifestItem struct {
+	Alias    string   `yaml:"alias" json:"alias"`
+	Name     string   `yaml:"name,omitempty" json:"name,omitempty"`
+	URL      string   `yaml:"url" json:"url"`
+	Variants Variants `yaml:"variants,omitempty" json:"variants,omitempty"`
+	Tags     []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+}
+
+type ChangeAction string
+
+const (
+	CHANGE_CREATE ChangeAction = "+"
+	CHANGE_UPDATE ChangeAction = "~"
+	CHANGE_DELETE ChangeAction = "-"
+)
+
+type ManifestChange struct {
+	Action ChangeAction
+	// Item has the desired fields, for updates and deletes it is the
+	// existing item
+	Item Item
+	// Diff describes the updated fields
+	Diff []string
+}
+
+// Plan is the list of changes which reconcile the items with a manifest,
+// parents go before their children and deletes go last.
+type Plan struct {
+	Changes []ManifestChange
+}
+
+func newPlanCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	var tag string
+	command := &cobra.Command{
+		Use:   "plan <manifest>",
+		Short: "Prints the changes which would make the items match the manifest",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := planFromFile(pb, args[0], tag)
+			if err != nil {
+				return err
+			}
+			plan.Print(cmd.OutOrStdout())
+			return nil
+		},
+	}
+	command.Flags().StringVar(&tag, "tag", "", "only manage the items with this tag")
+	return command
+}
+
+func newApplyCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	var tag string
+	command := &cobra.Command{
+		Use:   "apply <manifest>",
+		Short: "Makes the items match the manifest",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := planFromFile(pb, args[0], tag)
+			if err != nil {
+				return err
+			}
+			plan.Print(cmd.OutOrStdout())
+			if err := applyPlan(pb, plan); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Applied")
+			return nil
+		},
+	}
+	command.Flags().StringVar(&tag, "tag", "", "only manage the items with this tag")
+	return command
+}
+
+func newExportManifestCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	var tag string
+	var format string
+	command := &cobra.Command{
+		Use:   "manifest",
+		Short: "Exports items as a manifest for plan and apply",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			items, err := getAllItems(pb, tag, "")
+			if err != nil {
+				return err
+			}
+			return writeManifest(cmd.OutOrStdout(), newManifest(items), format)
+		},
+	}
+	command.Flags().StringVar(&tag, "tag", "", "only export the items with this tag")
+	command.Flags().StringVar(&format, "format", "yaml", "yaml or json")
+	return command
+}
+
+func newManifest(items []Item) Manifest {
+	manifest := Manifest{Items: make([]ManifestItem, 0, len(items))}
+	for _, item := range items {
+		manifest.Items = append(manifest.Items, ManifestItem{
+			Alias:    item.Path,
+			Name:     item.Name,
+			URL:      item.URL,
+			Variants: item.Variants,
+			Tags:     item.Tags,
+		})
+	}
+	return manifest
+}
+
+func writeManifest(w io.Writer, manifest Manifest, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(manifest)
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(manifest); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// readManifest reads JSON from the `.json` files and YAML from the rest.
+func readManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &manifest)
+	} else {
+		err = yaml.Unmarshal(data, &manifest)
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("%s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+func planFromFile(pb *pocketbase.PocketBase, path string, tag string) (Plan, error) {
+	manifest, err := readManifest(path)
+	if err != nil {
+		return Plan{}, err
+	}
+	existing, err := getAllItems(pb, "", "")
+	if err != nil {
+		return Plan{}, err
+	}
+	return planManifest(manifest, existing, tag)
+}
+
+// planManifest compares the manifest with the existing items. With a tag
+// only the existing items with that tag can be deleted and all items of
+// the manifest must have it. Deleting an item deletes its children as
+// well, so it is an error to delete the parent of an item which is kept.
+func planManifest(manifest Manifest, existing []Item, tag string) (Plan, error) {
+	plan := Plan{Changes: []ManifestChange{}}
+	byPath := make(map[string]Item, len(existing))
+	for _, item := range existing {
+		byPath[item.Path] = item
+	}
+	wanted := make(map[string]bool, len(manifest.Items))
+	for _, m := range manifest.Items {
+		path := strings.Join(strings.Fields(m.Alias), " ")
+		if path == "" {
+			return plan, fmt.Errorf("item with URL %q doesn't have an alias", m.URL)
+		}
+		if wanted[path] {
+			return plan, fmt.Errorf("%s: duplicate alias", path)
+		}
+		wanted[path] = true
+		if tag != "" && !slices.Contains(m.Tags, tag) {
+			return plan, fmt.Errorf("%s: doesn't have the %q tag", path, tag)
+		}
+		segments := strings.Fields(path)
+		item := Item{
+			Alias:    segments[len(segments)-1],
+			Name:     m.Name,
+			URL:      m.URL,
+			Variants: m.Variants,
+			Tags:     m.Tags,
+			Path:     path,
+		}
+		current, ok := byPath[path]
+		if !ok {
+			plan.Changes = append(plan.Changes, ManifestChange{Action: CHANGE_CREATE, Item: item})
+			continue
+		}
+		item.ID = current.ID
+		item.Parent = current.Parent
+		if diff := diffItems(current, item); len(diff) > 0 {
+			plan.Changes = append(plan.Changes, ManifestChange{Action: CHANGE_UPDATE, Item: item, Diff: diff})
+		}
+	}
+	deleted := make(map[string]bool)
+	for _, item := range existing {
+		if wanted[item.Path] || (tag != "" && !slices.Contains(item.Tags, tag)) {
+			continue
+		}
+		deleted[item.Path] = true
+		plan.Changes = append(plan.Changes, ManifestChange{Action: CHANGE_DELETE, Item: item})
+	}
+	kept := make([]string, 0, len(manifest.Items))
+	for _, m := range manifest.Items {
+		kept = append(kept, strings.Join(strings.Fields(m.Alias), " "))
+	}
+	for _, item := range existing {
+		if !deleted[item.Path] && !wanted[item.Path] {
+			kept = append(kept, item.Path)
+		}
+	}
+	for _, path := range kept {
+		for parent, ok := parentPath(path); ok; parent, ok = parentPath(parent) {
+			if deleted[parent] {
+				return plan, fmt.Errorf("%s: parent %q isn't in the manifest and would be deleted with its children", path, parent)
+			}
+		}
+	}
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		a, b := plan.Changes[i], plan.Changes[j]
+		if (a.Action == CHANGE_DELETE) != (b.Action == CHANGE_DELETE) {
+			return b.Action == CHANGE_DELETE
+		}
+		// Children are deleted before their parents
+		if a.Action == CHANGE_DELETE {
+			return depth(a.Item.Path) > depth(b.Item.Path)
+		}
+		return depth(a.Item.Path) < depth(b.Item.Path)
+	})
+	return plan, nil
+}
+
+func diffItems(current Item, wanted Item) []string {
+	diff := make([]string, 0)
+	if current.Name != wanted.Name {
+		diff = append(diff, fmt.Sprintf("name: %q -> %q", current.Name, wanted.Name))
+	}
+	if current.URL != wanted.URL {
+		diff = append(diff, fmt.Sprintf("url: %s -> %s", current.URL, wanted.URL))
+	}
+	if (len(current.Variants) > 0 || len(wanted.Variants) > 0) && !reflect.DeepEqual(current.Variants, wanted.Variants) {
+		diff = append(diff, fmt.Sprintf("variants: %v -> %v", map[int]string(current.Variants), map[int]string(wanted.Variants)))
+	}
+	if !slices.Equal(current.Tags, wanted.Tags) {
+		diff = append(diff, fmt.Sprintf("tags: %v -> %v", []string(current.Tags), wanted.Tags))
+	}
+	return diff
+}
+
+func depth(path string) int {
+	return strings.Count(path, " ")
+}
+
+func (p Plan) Print(w io.Writer) {
+	counts := make(map[ChangeAction]int)
+	for _, change := range p.Changes {
+		counts[change.Action]++
+		switch change.Action {
+		case CHANGE_UPDATE:
+			fmt.Fprintf(w, "%s %s\t%s\n", change.Action, change.Item.Path, strings.Join(change.Diff, "; "))
+		default:
+			fmt.Fprintf(w, "%s %s\t%s\n", change.Action, change.Item.Path, change.Item.URL)
+		}
+	}
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete\n", counts[CHANGE_CREATE], counts[CHANGE_UPDATE], counts[CHANGE_DELETE])
+}
+
+// applyPlan makes all changes in a single transaction, nothing is changed
+// when any of them fails.
+func applyPlan(pb *pocketbase.PocketBase, plan Plan) error {
+	existing, err := getAllItems(pb, "", "")
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]string, len(existing))
+	for _, item := range existing {
+		ids[item.Path] = item.ID
+	}
+	return pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		for _, change := range plan.Changes {
+			var err error
+			switch change.Action {
+			case CHANGE_CREATE:
+				item := change.Item
+				if parentPath, ok := parentPath(item.Path); ok {
+					parent, found := ids[parentPath]
+					if !found {
+						return fmt.Errorf("%s: parent %q doesn't exist", item.Path, parentPath)
+					}
+					item.Parent = parent
+				}
+				item, err = saveNewItem(txDao, item)
+				ids[item.Path] = item.ID
+			case CHANGE_UPDATE:
+				var record *models.Record
+				record, err = txDao.FindRecordById("items", change.Item.ID)
+				if err == nil {
+					setItemFields(record, change.Item)
+					err = txDao.SaveRecord(record)
+				}
+			case CHANGE_DELETE:
+				var record *models.Record
+				record, err = txDao.FindRecordById("items", change.Item.ID)
+				if err == nil {
+					err = txDao.DeleteRecord(record)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", change.Item.Path, err)
+			}
+		}
+		return nil
+	})
+}
+
+// parentPath returns the path of the parent of a nested item.
+func parentPath(path string) (string, bool) {
+	i := strings.LastIndexByte(path, ' ')
+	if i < 0 {
+		return "", false
+	}
+	return path[:i], true
+}
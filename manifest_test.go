@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	manifest := newManifest([]Item{github, githubPulls, google})
+	for _, format := range []string{"yaml", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeManifest(&buf, manifest, format); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "links."+format)
+			if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readManifest(path)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, buf.String())
+			}
+			if !reflect.DeepEqual(got, manifest) {
+				t.Errorf("got %+v, want %+v", got, manifest)
+			}
+		})
+	}
+}
+
+func TestReadManifestVariantKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		err  bool
+	}{
+		{name: "plain", file: "links.yaml", data: "items:\n  - alias: gh\n    variants:\n      0: https://github.com\n"},
+		{name: "quoted", file: "links.yml", data: "items:\n  - alias: gh\n    variants:\n      \"0\": https://github.com\n"},
+		{name: "json", file: "links.json", data: `{"items": [{"alias": "gh", "variants": {"0": "https://github.com"}}]}`},
+		{name: "json as yaml", file: "links.txt", data: `{"items": [{"alias": "gh", "variants": {"0": "https://github.com"}}]}`},
+		{name: "not a number", file: "links.yaml", data: "items:\n  - alias: gh\n    variants:\n      home: https://github.com\n", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			manifest, err := readManifest(path)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Variants{0: "https://github.com"}
+			if len(manifest.Items) != 1 || !reflect.DeepEqual(manifest.Items[0].Variants, want) {
+				t.Errorf("got %+v, want variants %v", manifest.Items, want)
+			}
+		})
+	}
+}
+
+func TestPlanManifest(t *testing.T) {
+	existing := []Item{github, githubPulls, githubIssues, google}
+	withTags := func(item Item, tags ...string) Item {
+		item.Tags = tags
+		return item
+	}
+	renamed := newManifest([]Item{github, githubPulls, githubIssues, duckduckgo})
+	renamed.Items[0].Name = "GitHub search"
+	renamed.Items = append(renamed.Items, ManifestItem{Alias: "gh  new", URL: "https://github.com/new"})
+	tests := []struct {
+		name     string
+		manifest Manifest
+		existing []Item
+		tag      string
+		want     []string
+		err      bool
+	}{
+		{
+			name:     "unchanged",
+			manifest: newManifest(existing),
+			existing: existing,
+			want:     []string{},
+		},
+		{
+			name:     "changes",
+			manifest: renamed,
+			existing: existing,
+			want:     []string{"~ gh", "+ ddg", "+ gh new", "- g"},
+		},
+		{
+			name:     "children deleted first",
+			manifest: newManifest([]Item{google}),
+			existing: existing,
+			want:     []string{"- gh pr", "- gh issues", "- gh"},
+		},
+		{
+			name:     "tag",
+			manifest: newManifest([]Item{withTags(duckduckgo, "team")}),
+			existing: []Item{github, withTags(google, "team")},
+			tag:      "team",
+			want:     []string{"+ ddg", "- g"},
+		},
+		{
+			name:     "item without the tag",
+			manifest: newManifest([]Item{duckduckgo}),
+			existing: existing,
+			tag:      "team",
+			err:      true,
+		},
+		{
+			name:     "deleted parent",
+			manifest: newManifest([]Item{githubPulls, githubIssues, google}),
+			existing: existing,
+			err:      true,
+		},
+		{
+			name:     "deleted parent of a new item",
+			manifest: Manifest{Items: []ManifestItem{{Alias: "gh new", URL: "https://github.com/new"}}},
+			existing: []Item{github},
+			err:      true,
+		},
+		{
+			name:     "deleted parent of an unmanaged item",
+			manifest: newManifest([]Item{withTags(google, "team")}),
+			existing: []Item{withTags(github, "team"), githubPulls, withTags(google, "team")},
+			tag:      "team",
+			err:      true,
+		},
+		{
+			name:     "duplicate alias",
+			manifest: Manifest{Items: []ManifestItem{{Alias: "gh pr", URL: "https://github.com"}, {Alias: "gh  pr", URL: "https://github.com"}}},
+			err:      true,
+		},
+		{
+			name:     "missing alias",
+			manifest: Manifest{Items: []ManifestItem{{Alias: " ", URL: "https://github.com"}}},
+			err:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planManifest(tt.manifest, tt.existing, tt.tag)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", plan.Changes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(plan.Changes))
+			for _, change := range plan.Changes {
+				got = append(got, string(change.Action)+" "+change.Item.Path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("39spxoreezeamnc")
+		if err != nil {
+			return err
+		}
+
+		// add
+		new_variants := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "q3xw8hnd",
+			"name": "variants",
+			"type": "json",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"maxSize": 2000000
+			}
+		}`), new_variants); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_variants)
+
+		return dao.SaveCollection(collection)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("39spxoreezeamnc")
+		if err != nil {
+			return err
+		}
+
+		// remove
+		collection.Schema.RemoveField("q3xw8hnd")
+
+		return dao.SaveCollection(collection)
+	})
+}
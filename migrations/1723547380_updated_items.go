@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("39spxoreezeamnc")
+		if err != nil {
+			return err
+		}
+
+		// add
+		new_parent := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "b5kzrp2m",
+			"name": "parent",
+			"type": "relation",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"collectionId": "39spxoreezeamnc",
+				"cascadeDelete": true,
+				"minSelect": null,
+				"maxSelect": 1,
+				"displayFields": null
+			}
+		}`), new_parent); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_parent)
+
+		return dao.SaveCollection(collection)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("39spxoreezeamnc")
+		if err != nil {
+			return err
+		}
+
+		// remove
+		collection.Schema.RemoveField("b5kzrp2m")
+
+		return dao.SaveCollection(collection)
+	})
+}
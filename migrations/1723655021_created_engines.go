@@ -0,0 +1,115 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		jsonData := `{
+			"id": "k7v2n9dqz4w1hxe",
+			"created": "2024-08-14 17:03:41.113Z",
+			"updated": "2024-08-14 17:03:41.113Z",
+			"name": "engines",
+			"type": "base",
+			"system": false,
+			"schema": [
+				{
+					"system": false,
+					"id": "t1mcw6ya",
+					"name": "name",
+					"type": "text",
+					"required": true,
+					"presentable": true,
+					"unique": false,
+					"options": {
+						"min": null,
+						"max": null,
+						"pattern": ""
+					}
+				},
+				{
+					"system": false,
+					"id": "fz0kq3ve",
+					"name": "alias",
+					"type": "text",
+					"required": false,
+					"presentable": false,
+					"unique": false,
+					"options": {
+						"min": null,
+						"max": null,
+						"pattern": ""
+					}
+				},
+				{
+					"system": false,
+					"id": "p8ugd4sl",
+					"name": "url",
+					"type": "text",
+					"required": true,
+					"presentable": false,
+					"unique": false,
+					"options": {
+						"min": null,
+						"max": null,
+						"pattern": ""
+					}
+				},
+				{
+					"system": false,
+					"id": "w2hn7jcx",
+					"name": "position",
+					"type": "number",
+					"required": false,
+					"presentable": false,
+					"unique": false,
+					"options": {
+						"min": null,
+						"max": null,
+						"noDecimal": true
+					}
+				}
+			],
+			"indexes": [],
+			"listRule": null,
+			"viewRule": null,
+			"createRule": null,
+			"updateRule": null,
+			"deleteRule": null,
+			"options": {}
+		}`
+
+		collection := &models.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		dao := daos.New(db)
+		if err := dao.SaveCollection(collection); err != nil {
+			return err
+		}
+
+		// Google used to be the hard-coded fallback, keep it as the default one
+		google := models.NewRecord(collection)
+		google.Set("name", "Google")
+		google.Set("alias", "g")
+		google.Set("url", "https://google.com/search?q={query}")
+		google.Set("position", 1)
+		return dao.SaveRecord(google)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("k7v2n9dqz4w1hxe")
+		if err != nil {
+			return err
+		}
+
+		return dao.DeleteCollection(collection)
+	})
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		// add
+		new_engine := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "r6oa1szk",
+			"name": "engine",
+			"type": "relation",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"collectionId": "k7v2n9dqz4w1hxe",
+				"cascadeDelete": false,
+				"minSelect": null,
+				"maxSelect": 1,
+				"displayFields": null
+			}
+		}`), new_engine); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_engine)
+
+		return dao.SaveCollection(collection)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		// remove
+		collection.Schema.RemoveField("r6oa1szk")
+
+		return dao.SaveCollection(collection)
+	})
+}
@@ -0,0 +1,131 @@
+package migrations
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		jsonData := `{
+			"id": "8mdw3qz0lyr5x1c",
+			"created": "2024-08-16 10:00:47.352Z",
+			"updated": "2024-08-16 10:00:47.352Z",
+			"name": "frecency",
+			"type": "base",
+			"system": false,
+			"schema": [
+				{
+					"system": false,
+					"id": "a4ejx9wk",
+					"name": "alias",
+					"type": "text",
+					"required": true,
+					"presentable": true,
+					"unique": false,
+					"options": {
+						"min": null,
+						"max": null,
+						"pattern": ""
+					}
+				},
+				{
+					"system": false,
+					"id": "h0vq2ltn",
+					"name": "device",
+					"type": "relation",
+					"required": false,
+					"presentable": false,
+					"unique": false,
+					"options": {
+						"collectionId": "rucpy694xgirors",
+						"cascadeDelete": true,
+						"minSelect": null,
+						"maxSelect": 1,
+						"displayFields": null
+					}
+				},
+				{
+					"system": false,
+					"id": "zc5rbm8u",
+					"name": "score",
+					"type": "number",
+					"required": false,
+					"presentable": false,
+					"unique": false,
+					"options": {
+						"min": null,
+						"max": null,
+						"noDecimal": false
+					}
+				}
+			],
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_Qm3vT8a` + "`" + ` ON ` + "`" + `frecency` + "`" + ` (` + "`" + `alias` + "`" + `, ` + "`" + `device` + "`" + `)"
+			],
+			"listRule": null,
+			"viewRule": null,
+			"createRule": null,
+			"updateRule": null,
+			"deleteRule": null,
+			"options": {}
+		}`
+
+		collection := &models.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		dao := daos.New(db)
+		if err := dao.SaveCollection(collection); err != nil {
+			return err
+		}
+
+		// Backfill the scores from the existing logs, the formula has to
+		// match the one used by the app
+		epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		halfLife := 30 * 24 * time.Hour
+		logs := []struct {
+			Alias   string         `db:"alias"`
+			Device  string         `db:"device"`
+			Created types.DateTime `db:"created"`
+		}{}
+		if err := db.Select("alias", "device", "created").From("logs").All(&logs); err != nil {
+			return err
+		}
+		scores := map[[2]string]float64{}
+		for _, log := range logs {
+			score := math.Exp2(float64(log.Created.Time().Sub(epoch)) / float64(halfLife))
+			scores[[2]string{log.Alias, ""}] += score
+			if log.Device != "" {
+				scores[[2]string{log.Alias, log.Device}] += score
+			}
+		}
+		for key, score := range scores {
+			record := models.NewRecord(collection)
+			record.Set("alias", key[0])
+			record.Set("device", key[1])
+			record.Set("score", score)
+			if err := dao.SaveRecord(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("8mdw3qz0lyr5x1c")
+		if err != nil {
+			return err
+		}
+
+		return dao.DeleteCollection(collection)
+	})
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		// add
+		new_personal_ranking := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "yk1f6rvo",
+			"name": "personal_ranking",
+			"type": "bool",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {}
+		}`), new_personal_ranking); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_personal_ranking)
+
+		return dao.SaveCollection(collection)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		// remove
+		collection.Schema.RemoveField("yk1f6rvo")
+
+		return dao.SaveCollection(collection)
+	})
+}
@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("39spxoreezeamnc")
+		if err != nil {
+			return err
+		}
+
+		// Duplicates would fail the unique index, the ones created later get
+		// their id appended to the alias.
+		_, err = db.NewQuery(`
+			UPDATE items SET alias = alias || '-' || id
+			WHERE EXISTS (
+				SELECT 1 FROM items i
+				WHERE i.parent = items.parent AND i.alias = items.alias
+				AND (i.created < items.created OR (i.created = items.created AND i.id < items.id))
+			)
+		`).Execute()
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal([]byte(`[
+			"CREATE UNIQUE INDEX `+"`"+`idx_Xw4pK2n`+"`"+` ON `+"`"+`items`+"`"+` (`+"`"+`parent`+"`"+`, `+"`"+`alias`+"`"+`)"
+		]`), &collection.Indexes); err != nil {
+			return err
+		}
+
+		// update
+		edit_url := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "vhlj4tyu",
+			"name": "url",
+			"type": "text",
+			"required": true,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"min": null,
+				"max": null,
+				"pattern": ""
+			}
+		}`), edit_url); err != nil {
+			return err
+		}
+		collection.Schema.AddField(edit_url)
+
+		// update
+		edit_alias := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "7gqkvgvc",
+			"name": "alias",
+			"type": "text",
+			"required": true,
+			"presentable": true,
+			"unique": false,
+			"options": {
+				"min": null,
+				"max": null,
+				"pattern": "^\\S+$"
+			}
+		}`), edit_alias); err != nil {
+			return err
+		}
+		collection.Schema.AddField(edit_alias)
+
+		return dao.SaveCollection(collection)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("39spxoreezeamnc")
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal([]byte(`[]`), &collection.Indexes); err != nil {
+			return err
+		}
+
+		// update
+		edit_url := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "vhlj4tyu",
+			"name": "url",
+			"type": "text",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"min": null,
+				"max": null,
+				"pattern": ""
+			}
+		}`), edit_url); err != nil {
+			return err
+		}
+		collection.Schema.AddField(edit_url)
+
+		// update
+		edit_alias := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "7gqkvgvc",
+			"name": "alias",
+			"type": "text",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"min": null,
+				"max": null,
+				"pattern": ""
+			}
+		}`), edit_alias); err != nil {
+			return err
+		}
+		collection.Schema.AddField(edit_alias)
+
+		return dao.SaveCollection(collection)
+	})
+}
@@ -0,0 +1,182 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal([]byte(`[
+			"CREATE UNIQUE INDEX `+"`"+`idx_yBvFP9E`+"`"+` ON `+"`"+`devices`+"`"+` (`+"`"+`name`+"`"+`)",
+			"CREATE UNIQUE INDEX `+"`"+`idx_Tq7hD2k`+"`"+` ON `+"`"+`devices`+"`"+` (`+"`"+`token_hash`+"`"+`) WHERE `+"`"+`token_hash`+"`"+` != ''"
+		]`), &collection.Indexes); err != nil {
+			return err
+		}
+
+		// update
+		edit_token := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "gjxltu50",
+			"name": "token",
+			"type": "text",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"min": null,
+				"max": null,
+				"pattern": ""
+			}
+		}`), edit_token); err != nil {
+			return err
+		}
+		collection.Schema.AddField(edit_token)
+
+		// add
+		new_token_hash := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "t8hq3vzn",
+			"name": "token_hash",
+			"type": "text",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"min": null,
+				"max": null,
+				"pattern": ""
+			}
+		}`), new_token_hash); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_token_hash)
+
+		// add
+		new_expires := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "e2mxk7wa",
+			"name": "expires",
+			"type": "date",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"min": "",
+				"max": ""
+			}
+		}`), new_expires); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_expires)
+
+		// add
+		new_last_seen := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "l5sd9pqe",
+			"name": "last_seen",
+			"type": "date",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"min": "",
+				"max": ""
+			}
+		}`), new_last_seen); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_last_seen)
+
+		// add
+		new_revoked := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "r0vk4ytb",
+			"name": "revoked",
+			"type": "bool",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {}
+		}`), new_revoked); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_revoked)
+
+		if err := dao.SaveCollection(collection); err != nil {
+			return err
+		}
+
+		// Existing tokens are replaced with their hashes and expire in a year,
+		// the same as the new ones
+		type device struct {
+			ID    string `db:"id"`
+			Token string `db:"token"`
+		}
+		devices := []device{}
+		if err := db.NewQuery("SELECT id, token FROM devices WHERE token != ''").All(&devices); err != nil {
+			return err
+		}
+		expires, err := types.ParseDateTime(time.Now().Add(365 * 24 * time.Hour))
+		if err != nil {
+			return err
+		}
+		for _, d := range devices {
+			hash := sha256.Sum256([]byte(d.Token))
+			_, err := db.NewQuery("UPDATE devices SET token = '', token_hash = {:hash}, expires = {:expires} WHERE id = {:id}").
+				Bind(dbx.Params{
+					"id":      d.ID,
+					"hash":    hex.EncodeToString(hash[:]),
+					"expires": expires.String(),
+				}).
+				Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal([]byte(`[
+			"CREATE UNIQUE INDEX `+"`"+`idx_yBvFP9E`+"`"+` ON `+"`"+`devices`+"`"+` (`+"`"+`name`+"`"+`)"
+		]`), &collection.Indexes); err != nil {
+			return err
+		}
+
+		// The hashes can't be turned back into tokens, devices have to get
+		// new tokens after reverting
+		collection.Schema.RemoveField("t8hq3vzn")
+		collection.Schema.RemoveField("e2mxk7wa")
+		collection.Schema.RemoveField("l5sd9pqe")
+		collection.Schema.RemoveField("r0vk4ytb")
+
+		return dao.SaveCollection(collection)
+	})
+}
@@ -0,0 +1,97 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("39spxoreezeamnc")
+		if err != nil {
+			return err
+		}
+
+		// Items without an owner are shared by the signed in users, personal
+		// items are only visible to their owner and can't be given away.
+		collection.ListRule = types.Pointer("@request.auth.id != \"\" && (owner = \"\" || owner = @request.auth.id)")
+
+		collection.ViewRule = types.Pointer("@request.auth.id != \"\" && (owner = \"\" || owner = @request.auth.id)")
+
+		collection.CreateRule = types.Pointer("@request.auth.id != \"\" && (@request.data.owner = \"\" || @request.data.owner = @request.auth.id)")
+
+		collection.UpdateRule = types.Pointer("@request.auth.id != \"\" && (owner = \"\" || owner = @request.auth.id) && (@request.data.owner:isset = false || @request.data.owner = owner)")
+
+		collection.DeleteRule = types.Pointer("@request.auth.id != \"\" && (owner = \"\" || owner = @request.auth.id)")
+
+		if err := json.Unmarshal([]byte(`[
+			"CREATE UNIQUE INDEX `+"`"+`idx_Xw4pK2n`+"`"+` ON `+"`"+`items`+"`"+` (`+"`"+`owner`+"`"+`, `+"`"+`parent`+"`"+`, `+"`"+`alias`+"`"+`)"
+		]`), &collection.Indexes); err != nil {
+			return err
+		}
+
+		// add
+		new_owner := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "o7wn3rkq",
+			"name": "owner",
+			"type": "relation",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"collectionId": "_pb_users_auth_",
+				"cascadeDelete": true,
+				"minSelect": null,
+				"maxSelect": 1,
+				"displayFields": null
+			}
+		}`), new_owner); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_owner)
+
+		return dao.SaveCollection(collection)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("39spxoreezeamnc")
+		if err != nil {
+			return err
+		}
+
+		collection.ListRule = nil
+
+		collection.ViewRule = nil
+
+		collection.CreateRule = nil
+
+		collection.UpdateRule = nil
+
+		collection.DeleteRule = nil
+
+		// Personal items would break the unique index
+		if _, err := db.NewQuery("DELETE FROM items WHERE owner != ''").Execute(); err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal([]byte(`[
+			"CREATE UNIQUE INDEX `+"`"+`idx_Xw4pK2n`+"`"+` ON `+"`"+`items`+"`"+` (`+"`"+`parent`+"`"+`, `+"`"+`alias`+"`"+`)"
+		]`), &collection.Indexes); err != nil {
+			return err
+		}
+
+		// remove
+		collection.Schema.RemoveField("o7wn3rkq")
+
+		return dao.SaveCollection(collection)
+	})
+}
@@ -0,0 +1,119 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		// Users can see, rename and delete their own devices, the tokens are
+		// only issued by the app.
+		collection.ListRule = types.Pointer("@request.auth.id != \"\" && user = @request.auth.id")
+
+		collection.ViewRule = types.Pointer("@request.auth.id != \"\" && user = @request.auth.id")
+
+		collection.UpdateRule = types.Pointer("@request.auth.id != \"\" && user = @request.auth.id && @request.data.user:isset = false && @request.data.token:isset = false && @request.data.token_hash:isset = false")
+
+		collection.DeleteRule = types.Pointer("@request.auth.id != \"\" && user = @request.auth.id")
+
+		// add
+		new_user := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "u2sr8mfc",
+			"name": "user",
+			"type": "relation",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"collectionId": "_pb_users_auth_",
+				"cascadeDelete": true,
+				"minSelect": null,
+				"maxSelect": 1,
+				"displayFields": null
+			}
+		}`), new_user); err != nil {
+			return err
+		}
+		collection.Schema.AddField(new_user)
+
+		if err := dao.SaveCollection(collection); err != nil {
+			return err
+		}
+
+		pairings, err := dao.FindCollectionByNameOrId("w3pq8xkv5ry2cnd")
+		if err != nil {
+			return err
+		}
+
+		// add
+		new_pairing_user := &schema.SchemaField{}
+		if err := json.Unmarshal([]byte(`{
+			"system": false,
+			"id": "u9pm4ckx",
+			"name": "user",
+			"type": "relation",
+			"required": false,
+			"presentable": false,
+			"unique": false,
+			"options": {
+				"collectionId": "_pb_users_auth_",
+				"cascadeDelete": true,
+				"minSelect": null,
+				"maxSelect": 1,
+				"displayFields": null
+			}
+		}`), new_pairing_user); err != nil {
+			return err
+		}
+		pairings.Schema.AddField(new_pairing_user)
+
+		return dao.SaveCollection(pairings)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		collection.ListRule = nil
+
+		collection.ViewRule = nil
+
+		collection.UpdateRule = nil
+
+		collection.DeleteRule = nil
+
+		// remove
+		collection.Schema.RemoveField("u2sr8mfc")
+
+		if err := dao.SaveCollection(collection); err != nil {
+			return err
+		}
+
+		pairings, err := dao.FindCollectionByNameOrId("w3pq8xkv5ry2cnd")
+		if err != nil {
+			return err
+		}
+
+		// remove
+		pairings.Schema.RemoveField("u9pm4ckx")
+
+		return dao.SaveCollection(pairings)
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		// Users can't lift a revocation or extend the expiry of their devices,
+		// revoking goes through the app.
+		collection.UpdateRule = types.Pointer("@request.auth.id != \"\" && user = @request.auth.id && @request.data.user:isset = false && @request.data.token:isset = false && @request.data.token_hash:isset = false && @request.data.revoked:isset = false && @request.data.expires:isset = false && @request.data.last_seen:isset = false")
+
+		return dao.SaveCollection(collection)
+	}, func(db dbx.Builder) error {
+		dao := daos.New(db)
+
+		collection, err := dao.FindCollectionByNameOrId("rucpy694xgirors")
+		if err != nil {
+			return err
+		}
+
+		collection.UpdateRule = types.Pointer("@request.auth.id != \"\" && user = @request.auth.id && @request.data.user:isset = false && @request.data.token:isset = false && @request.data.token_hash:isset = false")
+
+		return dao.SaveCollection(collection)
+	})
+}
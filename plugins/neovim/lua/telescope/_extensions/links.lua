@@ -27,6 +27,10 @@ end
 links.__items = {}
 links.__prompt = ""
 
+-- The token of a device, it is sent as `Authorization: Bearer <token>`
+links.__url = "http://localhost:8090"
+links.__token = os.getenv("LINKS_TOKEN")
+
 links.get_items = function()
 	return function(prompt)
 		links.__prompt = prompt
@@ -38,9 +42,11 @@ links.get_items = function()
 			return links.__items
 		end
 		prompt, _ = string.gsub(prompt, " ", "%%20")
-		-- TODO: move to settings
 		local tx, rx = async.control.channel.oneshot()
-		curl.get("http://localhost:8090/api/items?q=" .. prompt, {
+		curl.get(links.__url .. "/api/items?q=" .. prompt, {
+			headers = {
+				authorization = "Bearer " .. (links.__token or ""),
+			},
 			callback = vim.schedule_wrap(function(result)
 				tx(result)
 			end),
@@ -109,7 +115,10 @@ local function links_picker(opts)
 end
 
 return require("telescope").register_extension({
-	setup = function(ext_config, config) end,
+	setup = function(ext_config, config)
+		links.__url = ext_config.url or links.__url
+		links.__token = ext_config.token or links.__token
+	end,
 	exports = {
 		links = function(opts)
 			links_picker(opts)
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/spf13/cobra"
+)
+
+// Browser policies add the items as search engines to a fleet of managed
+// browsers, every item expands through `/api/expand` and Links itself is
+// the default engine.
+
+const DEFAULT_POLICY_KEYWORD = "l"
+
+// ChromeSearchEngine is an entry of the `ManagedSearchEngines` policy of
+// Chromium-based browsers.
+type ChromeSearchEngine struct {
+	Name       string `json:"name"`
+	Keyword    string `json:"keyword"`
+	SearchURL  string `json:"search_url"`
+	SuggestURL string `json:"suggest_url,omitempty"`
+	IsDefault  bool   `json:"is_default,omitempty"`
+}
+
+type ChromePolicy struct {
+	ManagedSearchEngines []ChromeSearchEngine `json:"ManagedSearchEngines"`
+}
+
+// FirefoxSearchEngine is an engine added by the `SearchEngines` policy.
+type FirefoxSearchEngine struct {
+	Name               string `json:"Name"`
+	URLTemplate        string `json:"URLTemplate"`
+	Method             string `json:"Method"`
+	Alias              string `json:"Alias"`
+	SuggestURLTemplate string `json:"SuggestURLTemplate,omitempty"`
+}
+
+type FirefoxPolicy struct {
+	Policies struct {
+		SearchEngines struct {
+			Add     []FirefoxSearchEngine `json:"Add"`
+			Default string                `json:"Default"`
+		} `json:"SearchEngines"`
+	} `json:"policies"`
+}
+
+// PolicyEngine is a browser agnostic search engine of a policy.
+type PolicyEngine struct {
+	Name       string
+	Keyword    string
+	SearchURL  string
+	SuggestURL string
+}
+
+// getBaseURL returns the public URL of the app, as set in the settings.
+func getBaseURL(pb *pocketbase.PocketBase) string {
+	return strings.TrimSuffix(pb.Settings().Meta.AppUrl, "/")
+}
+
+// getPolicyEngines returns Links itself followed by the top-level items with
+// the tag, each of them expands the query prefixed with its alias. Managed
+// browsers don't have the cookie, so the device token is added to the URLs.
+func getPolicyEngines(pb *pocketbase.PocketBase, tag string, keyword string, token string) ([]PolicyEngine, error) {
+	baseURL := getBaseURL(pb)
+	items, err := getAllItems(pb, tag, "")
+	if err != nil {
+		return nil, err
+	}
+	tokenParam := ""
+	if token != "" {
+		if _, ok := getDeviceByToken(pb, token); !ok {
+			return nil, errors.New("the token doesn't belong to an active device")
+		}
+		tokenParam = "&token=" + url.QueryEscape(token)
+	}
+	engines := []PolicyEngine{{
+		Name:       "Links",
+		Keyword:    keyword,
+		SearchURL:  baseURL + "/api/expand?q={searchTerms}" + tokenParam,
+		SuggestURL: baseURL + "/api/opensearch?q={searchTerms}" + tokenParam,
+	}}
+	names := map[string]bool{"Links": true}
+	for _, item := range items {
+		if item.Parent != "" || item.Alias == keyword {
+			continue
+		}
+		// Browsers require unique names
+		name := item.Name
+		if name == "" {
+			name = item.Alias
+		}
+		if names[name] {
+			name = fmt.Sprintf("%s (%s)", name, item.Alias)
+		}
+		names[name] = true
+		engines = append(engines, PolicyEngine{
+			Name:      name,
+			Keyword:   item.Alias,
+			SearchURL: baseURL + "/api/expand?q=" + url.QueryEscape(item.Alias) + "+{searchTerms}" + tokenParam,
+		})
+	}
+	return engines, nil
+}
+
+func newChromePolicy(engines []PolicyEngine) ChromePolicy {
+	policy := ChromePolicy{ManagedSearchEngines: make([]ChromeSearchEngine, 0, len(engines))}
+	for i, engine := range engines {
+		policy.ManagedSearchEngines = append(policy.ManagedSearchEngines, ChromeSearchEngine{
+			Name:       engine.Name,
+			Keyword:    engine.Keyword,
+			SearchURL:  engine.SearchURL,
+			SuggestURL: engine.SuggestURL,
+			IsDefault:  i == 0,
+		})
+	}
+	return policy
+}
+
+func newFirefoxPolicy(engines []PolicyEngine) FirefoxPolicy {
+	var policy FirefoxPolicy
+	policy.Policies.SearchEngines.Add = make([]FirefoxSearchEngine, 0, len(engines))
+	for _, engine := range engines {
+		policy.Policies.SearchEngines.Add = append(policy.Policies.SearchEngines.Add, FirefoxSearchEngine{
+			Name:               engine.Name,
+			URLTemplate:        engine.SearchURL,
+			Method:             "GET",
+			Alias:              engine.Keyword,
+			SuggestURLTemplate: engine.SuggestURL,
+		})
+	}
+	if len(engines) > 0 {
+		policy.Policies.SearchEngines.Default = engines[0].Name
+	}
+	return policy
+}
+
+// getPolicy builds the policy for the browser, either `chrome` or `firefox`.
+func getPolicy(pb *pocketbase.PocketBase, browser string, tag string, keyword string, token string) (any, error) {
+	engines, err := getPolicyEngines(pb, tag, keyword, token)
+	if err != nil {
+		return nil, err
+	}
+	switch browser {
+	case "chrome":
+		return newChromePolicy(engines), nil
+	case "firefox":
+		return newFirefoxPolicy(engines), nil
+	default:
+		return nil, fmt.Errorf("unknown browser %q", browser)
+	}
+}
+
+func newPolicyCommand(pb *pocketbase.PocketBase) *cobra.Command {
+	var tag string
+	var keyword string
+	var token string
+	command := &cobra.Command{
+		Use:       "policy <chrome|firefox>",
+		Short:     "Prints a browser policy which adds the items as search engines",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"chrome", "firefox"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy, err := getPolicy(pb, args[0], tag, keyword, token)
+			if err != nil {
+				return err
+			}
+			return writePolicy(cmd.OutOrStdout(), policy)
+		},
+	}
+	command.Flags().StringVar(&tag, "tag", "", "only add the items with this tag")
+	command.Flags().StringVar(&keyword, "keyword", DEFAULT_POLICY_KEYWORD, "keyword of the default Links engine")
+	command.Flags().StringVar(&token, "token", "", "device token added to the URLs, see `links device create`")
+	return command
+}
+
+func writePolicy(w io.Writer, policy any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	// The policies are edited by hand, `&` is easier to read than `\u0026`
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(policy)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query is a search query split into an alias and its arguments.
+// The tokenizer follows shell rules: arguments are separated by any amount
+// of whitespace, can be wrapped in "double" or 'single' quotes and special
+// characters can be escaped with a backslash. Quotes only start an argument
+// at its beginning, so apostrophes inside of words are kept as is.
+//
+// An unquoted `!alias` token anywhere in the query forces the alias, it is
+// moved to the front and the rest of the tokens become its arguments.
+type Query struct {
+	Raw    string
+	Tokens []string
+	// Complete is true when the alias is followed by whitespace, meaning
+	// that the user has finished typing it.
+	Complete bool
+	// Trailing is true when the query ends with whitespace, meaning that
+	// the last token is finished as well.
+	Trailing bool
+	// Bang is true when the alias was forced with the `!alias` syntax.
+	Bang bool
+}
+
+func parseQuery(q string) Query {
+	query := Query{Raw: q, Tokens: []string{}}
+	var token strings.Builder
+	var quote rune
+	started, escaped, bare := false, false, true
+	bang := -1
+	finish := func() {
+		if bang < 0 && bare && token.Len() > 1 && strings.HasPrefix(token.String(), "!") {
+			bang = len(query.Tokens)
+		}
+		query.Tokens = append(query.Tokens, token.String())
+		token.Reset()
+		started, bare = false, true
+	}
+	for _, r := range q {
+		switch {
+		case escaped:
+			token.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			started, bare = true, false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				token.WriteRune(r)
+			}
+		case (r == '"' || r == '\'') && !started:
+			quote = r
+			started, bare = true, false
+		case unicode.IsSpace(r):
+			if started {
+				finish()
+			}
+			if len(query.Tokens) > 0 {
+				query.Complete = true
+				query.Trailing = true
+			}
+			continue
+		default:
+			token.WriteRune(r)
+			started = true
+		}
+		query.Trailing = false
+	}
+	if started {
+		finish()
+	}
+	if bang >= 0 {
+		alias := strings.TrimPrefix(query.Tokens[bang], "!")
+		rest := append(query.Tokens[:bang:bang], query.Tokens[bang+1:]...)
+		query.Tokens = append([]string{alias}, rest...)
+		query.Complete = true
+		query.Bang = true
+	}
+	return query
+}
+
+func (q Query) Alias() string {
+	if len(q.Tokens) == 0 {
+		return ""
+	}
+	return q.Tokens[0]
+}
+
+func (q Query) Args() []string {
+	if len(q.Tokens) < 2 {
+		return []string{}
+	}
+	return q.Tokens[1:]
+}
+
+// SearchTerms are the tokens sent to a fallback search engine when the alias
+// doesn't exist, an unknown bang is dropped and the rest is searched for.
+func (q Query) SearchTerms() []string {
+	if q.Bang {
+		return q.Args()
+	}
+	return q.Tokens
+}
+
+// quoteArg quotes an argument when parseQuery would split or change it,
+// so that completions can be typed back as they are.
+func quoteArg(arg string) string {
+	special := strings.ContainsFunc(arg, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '\'' || r == '\\'
+	})
+	if arg != "" && !special && !strings.HasPrefix(arg, "!") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// WithAlias returns a copy of the query with the alias replaced.
+func (q Query) WithAlias(alias string) Query {
+	tokens := append([]string{alias}, q.Args()...)
+	q.Tokens = tokens
+	return q
+}
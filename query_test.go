@@ -0,0 +1,89 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		q        string
+		tokens   []string
+		complete bool
+		trailing bool
+		bang     bool
+	}{
+		{q: "", tokens: []string{}},
+		{q: "   ", tokens: []string{}},
+		{q: "gh", tokens: []string{"gh"}},
+		{q: "gh ", tokens: []string{"gh"}, complete: true, trailing: true},
+		{q: "  gh   pr  12", tokens: []string{"gh", "pr", "12"}, complete: true},
+		{q: "gh pr 12 ", tokens: []string{"gh", "pr", "12"}, complete: true, trailing: true},
+		{q: `g "hello world"`, tokens: []string{"g", "hello world"}, complete: true},
+		{q: `g 'a "b" c'`, tokens: []string{"g", `a "b" c`}, complete: true},
+		{q: `g "it's"`, tokens: []string{"g", "it's"}, complete: true},
+		{q: `g don't panic`, tokens: []string{"g", "don't", "panic"}, complete: true},
+		{q: `g a\ b`, tokens: []string{"g", "a b"}, complete: true},
+		{q: `g \"quoted\"`, tokens: []string{"g", `"quoted"`}, complete: true},
+		{q: `g '\n'`, tokens: []string{"g", `\n`}, complete: true},
+		{q: `g ""`, tokens: []string{"g", ""}, complete: true},
+		{q: `g "unterminated`, tokens: []string{"g", "unterminated"}, complete: true},
+		{q: "how to !gh", tokens: []string{"gh", "how", "to"}, complete: true, bang: true},
+		{q: "!gh", tokens: []string{"gh"}, complete: true, bang: true},
+		{q: "a !b !c", tokens: []string{"b", "a", "!c"}, complete: true, bang: true},
+		{q: "g !", tokens: []string{"g", "!"}, complete: true},
+		{q: `g "!gh"`, tokens: []string{"g", "!gh"}, complete: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			query := parseQuery(tt.q)
+			if !slices.Equal(query.Tokens, tt.tokens) {
+				t.Errorf("tokens = %q, want %q", query.Tokens, tt.tokens)
+			}
+			if query.Complete != tt.complete {
+				t.Errorf("complete = %v, want %v", query.Complete, tt.complete)
+			}
+			if query.Trailing != tt.trailing {
+				t.Errorf("trailing = %v, want %v", query.Trailing, tt.trailing)
+			}
+			if query.Bang != tt.bang {
+				t.Errorf("bang = %v, want %v", query.Bang, tt.bang)
+			}
+		})
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{arg: "12", want: "12"},
+		{arg: "don't", want: `"don't"`},
+		{arg: "hello world", want: `"hello world"`},
+		{arg: "tab\there", want: "\"tab\there\""},
+		{arg: `say "hi"`, want: `"say \"hi\""`},
+		{arg: `C:\links`, want: `"C:\\links"`},
+		{arg: "!gh", want: `"!gh"`},
+		{arg: "", want: `""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got := quoteArg(tt.arg)
+			if got != tt.want {
+				t.Errorf("quoteArg = %s, want %s", got, tt.want)
+			}
+			query := parseQuery("g x " + got)
+			if !slices.Equal(query.Args(), []string{"x", tt.arg}) || query.Bang {
+				t.Errorf("parsed back as %q", query.Tokens)
+			}
+		})
+	}
+}
+
+func TestQueryWithAlias(t *testing.T) {
+	query := parseQuery("hg links").WithAlias("gh")
+	if query.Alias() != "gh" || !slices.Equal(query.Args(), []string{"links"}) {
+		t.Errorf("tokens = %q, want [gh links]", query.Tokens)
+	}
+}
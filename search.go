@@ -0,0 +1,152 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// The full-text index requires SQLite with FTS5, which is always available
+// in CGO_ENABLED=0 builds. CGO builds need the `sqlite_fts5` build tag,
+// without it the search falls back to plain LIKE matching.
+var ftsEnabled bool
+
+const SEARCH_LIMIT = 10
+
+// setupSearchIndex creates the FTS5 table and fills it with all items, after
+// that it is kept in sync by the model hooks.
+func setupSearchIndex(pb *pocketbase.PocketBase) {
+	db := pb.Dao().DB()
+	_, err := db.NewQuery("CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5(item_id UNINDEXED, alias, name, url, tags, tokenize = 'unicode61')").Execute()
+	if err != nil {
+		log.Printf("full-text search is disabled: %v", err)
+		return
+	}
+	err = pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		if _, err := txDao.DB().NewQuery("DELETE FROM items_fts").Execute(); err != nil {
+			return err
+		}
+		_, err := txDao.DB().NewQuery("INSERT INTO items_fts (item_id, alias, name, url, tags) SELECT id, alias, name, url, tags FROM items").Execute()
+		return err
+	})
+	if err != nil {
+		log.Printf("full-text search is disabled: %v", err)
+		return
+	}
+	ftsEnabled = true
+}
+
+func registerSearchHooks(pb *pocketbase.PocketBase) {
+	sync := func(e *core.ModelEvent) error {
+		return indexItem(e.Dao, e.Model.GetId())
+	}
+	pb.OnModelAfterCreate("items").Add(sync)
+	pb.OnModelAfterUpdate("items").Add(sync)
+	pb.OnModelAfterDelete("items").Add(sync)
+}
+
+// indexItem replaces the indexed copy of the item, deleted items are only
+// removed from the index.
+func indexItem(dao *daos.Dao, id string) error {
+	if !ftsEnabled {
+		return nil
+	}
+	params := dbx.Params{"id": id}
+	if _, err := dao.DB().NewQuery("DELETE FROM items_fts WHERE item_id = {:id}").Bind(params).Execute(); err != nil {
+		return err
+	}
+	_, err := dao.DB().NewQuery("INSERT INTO items_fts (item_id, alias, name, url, tags) SELECT id, alias, name, url, tags FROM items WHERE id = {:id}").Bind(params).Execute()
+	return err
+}
+
+// searchItems finds items by their alias, name, URL or tags, the best
+// matches go first.
+func searchItems(pb *pocketbase.PocketBase, q string, limit int, user string) []Item {
+	terms := strings.Fields(q)
+	items := make([]Item, 0)
+	if len(terms) == 0 {
+		return items
+	}
+	if ftsEnabled {
+		// Every term is quoted to escape the FTS syntax and matched as a prefix
+		match := make([]string, len(terms))
+		for i, term := range terms {
+			match[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+		}
+		pb.Dao().DB().
+			NewQuery("SELECT i.id, i.parent, i.owner, i.alias, i.name, i.url, i.variants, i.tags FROM items_fts f JOIN items i ON i.id = f.item_id WHERE items_fts MATCH {:match} AND " + visibleItems("i") + " ORDER BY bm25(items_fts, 0, 10.0, 5.0, 1.0, 2.0), i.alias LIMIT {:limit}").
+			Bind(dbx.Params{
+				"match": strings.Join(match, " "),
+				"limit": limit,
+				"user":  user,
+			}).
+			All(&items)
+		return withParentPaths(pb, items)
+	}
+	where := make([]dbx.Expression, len(terms))
+	for i, term := range terms {
+		where[i] = dbx.Or(
+			dbx.Like("alias", term),
+			dbx.Like("name", term),
+			dbx.Like("url", term),
+			dbx.Like("tags", term),
+		)
+	}
+	pb.Dao().DB().
+		Select("id", "parent", "owner", "alias", "name", "url", "variants", "tags").
+		From("items").
+		Where(dbx.And(where...)).
+		AndWhere(visibleItemsExp(user)).
+		OrderBy("alias").
+		Limit(int64(limit)).
+		All(&items)
+	return withParentPaths(pb, items)
+}
+
+// withParentPaths fills the paths of items, which can be anywhere in the
+// hierarchy.
+func withParentPaths(pb *pocketbase.PocketBase, items []Item) []Item {
+	for i := range items {
+		path := []string{items[i].Alias}
+		seen := map[string]bool{items[i].ID: true}
+		parent := items[i].Parent
+		for parent != "" && !seen[parent] {
+			seen[parent] = true
+			var p Item
+			err := pb.Dao().DB().
+				Select("id", "parent", "alias").
+				From("items").
+				Where(dbx.HashExp{"id": parent}).
+				One(&p)
+			if err != nil {
+				break
+			}
+			path = append([]string{p.Alias}, path...)
+			parent = p.Parent
+		}
+		items[i].Path = strings.Join(path, " ")
+	}
+	return items
+}
+
+// mergeItems appends the items which are not in the list yet.
+func mergeItems(items []Item, more []Item, limit int) []Item {
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		seen[item.ID] = true
+	}
+	for _, item := range more {
+		if len(items) >= limit {
+			break
+		}
+		if !seen[item.ID] {
+			seen[item.ID] = true
+			items = append(items, item)
+		}
+	}
+	return items
+}
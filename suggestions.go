@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+)
+
+// Suggestions is a response of the OpenSearch suggestions extension, the
+// descriptions and the query URLs are aligned with the completions.
+// https://github.com/dewitt/opensearch/blob/master/mediawiki/Specifications/OpenSearch/Extensions/Suggestions/1.1/Draft%201.wiki
+type Suggestions struct {
+	Query        string
+	Completions  []string
+	Descriptions []string
+	URLs         []string
+}
+
+// newSuggestions completes the query with the found items. Items which are
+// being typed are completed without arguments, the expanded item and the
+// typo corrections keep the arguments of the query.
+func newSuggestions(q string, result ItemsResult) Suggestions {
+	s := Suggestions{
+		Query:        q,
+		Completions:  []string{},
+		Descriptions: []string{},
+		URLs:         []string{},
+	}
+	query := parseQuery(q)
+	for _, item := range result.Items {
+		var expansion Expansion
+		switch {
+		case result.Expansion.URL != "" && item.Path == result.Expansion.Alias:
+			expansion = result.Expansion
+		case result.State == DID_YOU_MEAN:
+			expansion = expand(item, query.Args())
+		default:
+			expansion = expand(item, []string{})
+		}
+		s.add(expansion.Alias, expansion.Args, item.Name, expansion.URL)
+	}
+	// Nothing matched, the query goes to a search engine
+	if len(result.Items) == 0 && result.Engine != "" && result.Expansion.URL != "" {
+		s.add(strings.TrimSpace(q), []string{}, result.Engine, result.Expansion.URL)
+		// The other engines are completed with their aliases, so that the
+		// query goes to them when the completion is picked
+		for _, engine := range result.OtherEngines {
+			if engine.Alias != "" {
+				s.add(engine.Alias, engine.Args, engine.Name, engine.URL)
+			}
+		}
+	}
+	return s
+}
+
+func (s *Suggestions) add(alias string, args []string, description string, url string) {
+	completion := alias
+	for _, arg := range args {
+		completion += " " + quoteArg(arg)
+	}
+	s.Completions = append(s.Completions, completion)
+	s.Descriptions = append(s.Descriptions, description)
+	s.URLs = append(s.URLs, url)
+}
+
+// MarshalJSON uses the `[query, completions, descriptions, urls]` format.
+func (s Suggestions) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{s.Query, s.Completions, s.Descriptions, s.URLs})
+}
+
+type suggestionsXML struct {
+	XMLName xml.Name        `xml:"http://opensearch.org/searchsuggest2 SearchSuggestion"`
+	Query   string          `xml:"Query"`
+	Items   []suggestionXML `xml:"Section>Item"`
+}
+
+type suggestionXML struct {
+	Text        string `xml:"Text"`
+	Description string `xml:"Description,omitempty"`
+	URL         string `xml:"Url,omitempty"`
+}
+
+// XML uses the XML suggestions format, which is requested with
+// `format=xml`.
+func (s Suggestions) XML() ([]byte, error) {
+	v := suggestionsXML{Query: s.Query, Items: make([]suggestionXML, 0, len(s.Completions))}
+	for i, completion := range s.Completions {
+		v.Items = append(v.Items, suggestionXML{
+			Text:        completion,
+			Description: s.Descriptions[i],
+			URL:         s.URLs[i],
+		})
+	}
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+var (
+	github = Item{
+		ID:       "gh",
+		Alias:    "gh",
+		Name:     "GitHub",
+		URL:      "https://github.com/search?q={query}",
+		Variants: Variants{0: "https://github.com"},
+		Path:     "gh",
+	}
+	githubPulls = Item{
+		ID:     "pr",
+		Parent: "gh",
+		Alias:  "pr",
+		Name:   "Pull requests",
+		URL:    "https://github.com/biozz/links/pull/{number:path}",
+		Path:   "gh pr",
+	}
+	githubIssues = Item{
+		ID:     "issues",
+		Parent: "gh",
+		Alias:  "issues",
+		URL:    "https://github.com/biozz/links/issues?q={query?}",
+		Path:   "gh issues",
+	}
+	google = Item{
+		ID:    "google",
+		Alias: "g",
+		Name:  "Google",
+		URL:   "https://google.com/search?q={query}",
+		Path:  "g",
+	}
+	duckduckgo = Item{
+		ID:    "duckduckgo",
+		Alias: "ddg",
+		Name:  "DuckDuckGo",
+		URL:   "https://duckduckgo.com/?q={query}",
+		Path:  "ddg",
+	}
+	// bing has no alias, so it can't be completed
+	bing = Item{
+		ID:   "bing",
+		Name: "Bing",
+		URL:  "https://www.bing.com/search?q={query}",
+	}
+)
+
+func TestSuggestions(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      string
+		result ItemsResult
+	}{
+		{
+			name:   "empty",
+			q:      "",
+			result: ItemsResult{State: NEW_ITEM},
+		},
+		{
+			name: "prefix",
+			q:    "g",
+			result: ItemsResult{
+				State:     MULTIPLE_ITEMS,
+				Items:     []Item{google, github},
+				Expansion: expand(google, []string{}),
+			},
+		},
+		{
+			name: "args",
+			q:    "gh pr 12",
+			result: ItemsResult{
+				State:     ARGS_MODE,
+				Items:     []Item{githubPulls},
+				Expansion: expand(githubPulls, []string{"12"}),
+			},
+		},
+		{
+			name: "quoted",
+			q:    `g "hello world" it's`,
+			result: ItemsResult{
+				State:     ARGS_MODE,
+				Items:     []Item{google},
+				Expansion: expand(google, []string{"hello world", "it's"}),
+			},
+		},
+		{
+			name: "children",
+			q:    "gh ",
+			result: ItemsResult{
+				State:     ARGS_MODE,
+				Items:     []Item{githubIssues, githubPulls},
+				Expansion: expand(github, []string{}),
+			},
+		},
+		{
+			name: "did_you_mean",
+			q:    "hg links & co",
+			result: ItemsResult{
+				State: DID_YOU_MEAN,
+				Items: []Item{github},
+			},
+		},
+		{
+			name: "engine",
+			q:    "what is <html>",
+			result: ItemsResult{
+				State:     ARGS_MODE,
+				Items:     []Item{},
+				Engine:    "Google",
+				Expansion: expand(google, []string{"what", "is", "<html>"}),
+			},
+		},
+		{
+			name: "other_engines",
+			q:    "rust traits",
+			result: ItemsResult{
+				State:     ARGS_MODE,
+				Items:     []Item{},
+				Engine:    "Google",
+				Expansion: expand(google, []string{"rust", "traits"}),
+				OtherEngines: []EngineExpansion{
+					{Name: "DuckDuckGo", Expansion: expand(duckduckgo, []string{"rust", "traits"})},
+					{Name: "Bing", Expansion: expand(bing, []string{"rust", "traits"})},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := newSuggestions(tt.q, tt.result)
+			data, err := json.MarshalIndent(suggestions, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tt.name+".json", data)
+			data, err = suggestions.XML()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tt.name+".xml", data)
+		})
+	}
+}
+
+func assertGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "suggestions", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s: %v, run the tests with -update to create it", path, err)
+	}
+	if string(expected) != string(actual) {
+		t.Errorf("%s doesn't match:\n%s\nexpected:\n%s", path, actual, expected)
+	}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// URL templates support named placeholders in curly braces:
+//
+//	{query}        required placeholder
+//	{project=CORE} placeholder with a default value
+//	{page?}        optional placeholder, dropped when no argument is given
+//
+// Each placeholder can specify how its value is encoded, ex. `{repo:path}`
+// or `{page:raw?}`, see EncodingMode for the available modes.
+//
+// Legacy `%s` tokens are still supported, each one is an unnamed positional
+// placeholder. When there are more arguments than placeholders, the last
+// placeholder receives the rest of them joined with spaces.
+
+var errUnbalancedPlaceholder = errors.New("unbalanced placeholder braces")
+
+type EncodingMode string
+
+const (
+	// ENCODE_QUERY escapes the value to be used as a query parameter
+	ENCODE_QUERY EncodingMode = "query"
+	// ENCODE_PATH escapes the value to be used in a path, slashes are kept
+	ENCODE_PATH EncodingMode = "path"
+	// ENCODE_RAW inserts the value as is, escaping only characters which
+	// are never valid in a URL
+	ENCODE_RAW    EncodingMode = "raw"
+	ENCODE_BASE64 EncodingMode = "base64"
+	// ENCODE_SLUG lowercases the value and joins the words with dashes
+	ENCODE_SLUG EncodingMode = "slug"
+)
+
+var encodingModes = map[EncodingMode]func(string) string{
+	ENCODE_QUERY:  url.QueryEscape,
+	ENCODE_PATH:   pathEscape,
+	ENCODE_RAW:    rawEscape,
+	ENCODE_BASE64: func(s string) string { return base64.URLEncoding.EncodeToString([]byte(s)) },
+	ENCODE_SLUG:   func(s string) string { return url.PathEscape(slugify(s)) },
+}
+
+type Placeholder struct {
+	Name       string
+	Default    string
+	HasDefault bool
+	Optional   bool
+	Mode       EncodingMode
+	// QueryKey is set when the placeholder is the whole value of a query
+	// parameter, so that an empty optional value can drop the parameter.
+	QueryKey string
+}
+
+func (p Placeholder) Required() bool {
+	return !p.Optional && !p.HasDefault
+}
+
+func (p Placeholder) Encode(value string) string {
+	encode, ok := encodingModes[p.Mode]
+	if !ok {
+		encode = url.QueryEscape
+	}
+	return encode(value)
+}
+
+type templateSegment struct {
+	literal     string
+	placeholder int
+}
+
+type URLTemplate struct {
+	Source       string
+	Placeholders []Placeholder
+	segments     []templateSegment
+}
+
+var templateCache sync.Map
+
+// compileTemplate parses the template once and caches it by its source,
+// malformed templates fall back to the legacy `%s` syntax.
+func compileTemplate(source string) *URLTemplate {
+	if cached, ok := templateCache.Load(source); ok {
+		return cached.(*URLTemplate)
+	}
+	tmpl, err := parseTemplate(source)
+	if err != nil {
+		tmpl = parseLegacyTemplate(source)
+	}
+	templateCache.Store(source, tmpl)
+	return tmpl
+}
+
+// parseLegacyTemplate only understands `%s` tokens, it is used for URLs
+// which have curly braces of their own.
+func parseLegacyTemplate(source string) *URLTemplate {
+	tmpl := &URLTemplate{Source: source}
+	pos := 0
+	for i, part := range strings.Split(source, "%s") {
+		if i > 0 {
+			tmpl.Placeholders = append(tmpl.Placeholders, Placeholder{Mode: legacyMode(source, pos)})
+			tmpl.segments = append(tmpl.segments, templateSegment{placeholder: len(tmpl.Placeholders) - 1})
+		}
+		if part != "" {
+			tmpl.segments = append(tmpl.segments, templateSegment{literal: part, placeholder: -1})
+		}
+		pos += len(part) + len("%s")
+	}
+	return tmpl
+}
+
+// legacyMode picks the encoding of a `%s` placeholder by its position,
+// so that it keeps working both in paths and in query strings.
+func legacyMode(source string, pos int) EncodingMode {
+	if query := strings.IndexByte(source, '?'); query >= 0 && query < pos {
+		return ENCODE_QUERY
+	}
+	return ENCODE_PATH
+}
+
+func parseTemplate(source string) (*URLTemplate, error) {
+	tmpl := &URLTemplate{Source: source}
+	names := make(map[string]int)
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			tmpl.segments = append(tmpl.segments, templateSegment{literal: literal.String(), placeholder: -1})
+			literal.Reset()
+		}
+	}
+	for i := 0; i < len(source); i++ {
+		switch {
+		case strings.HasPrefix(source[i:], "%s"):
+			flush()
+			tmpl.Placeholders = append(tmpl.Placeholders, Placeholder{Mode: legacyMode(source, i)})
+			tmpl.segments = append(tmpl.segments, templateSegment{placeholder: len(tmpl.Placeholders) - 1})
+			i++
+		case source[i] == '{':
+			end := strings.IndexByte(source[i:], '}')
+			if end < 0 {
+				return nil, errUnbalancedPlaceholder
+			}
+			p, err := parsePlaceholder(source[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			p.QueryKey = queryKeyAt(source, i, i+end+1)
+			flush()
+			idx, ok := names[p.Name]
+			if !ok {
+				tmpl.Placeholders = append(tmpl.Placeholders, p)
+				idx = len(tmpl.Placeholders) - 1
+				names[p.Name] = idx
+			}
+			tmpl.segments = append(tmpl.segments, templateSegment{placeholder: idx})
+			i += end
+		case source[i] == '}':
+			return nil, errUnbalancedPlaceholder
+		default:
+			literal.WriteByte(source[i])
+		}
+	}
+	flush()
+	return tmpl, nil
+}
+
+func parsePlaceholder(body string) (Placeholder, error) {
+	var p Placeholder
+	if name, def, ok := strings.Cut(body, "="); ok {
+		if strings.HasSuffix(def, "?") {
+			return p, fmt.Errorf("placeholder %q can't have both a default and be optional", name)
+		}
+		body = name
+		p.Default = def
+		p.HasDefault = true
+	} else if name, ok := strings.CutSuffix(body, "?"); ok {
+		body = name
+		p.Optional = true
+	}
+	p.Mode = ENCODE_QUERY
+	if name, mode, ok := strings.Cut(body, ":"); ok {
+		if _, known := encodingModes[EncodingMode(mode)]; !known {
+			return p, fmt.Errorf("unknown encoding mode %q", mode)
+		}
+		body = name
+		p.Mode = EncodingMode(mode)
+	}
+	if !isPlaceholderName(body) {
+		return p, fmt.Errorf("invalid placeholder name %q", body)
+	}
+	p.Name = body
+	return p, nil
+}
+
+func isPlaceholderName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		isLetter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && !(isDigit && i > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// queryKeyAt returns the query parameter name when the placeholder spanning
+// source[start:end] is the whole value of that parameter.
+func queryKeyAt(source string, start, end int) string {
+	query := queryStart(source)
+	if query < 0 || query > start {
+		return ""
+	}
+	after := source[end:]
+	if after != "" && after[0] != '&' && after[0] != '#' {
+		return ""
+	}
+	param := source[query+1 : start]
+	if i := strings.LastIndexByte(param, '&'); i >= 0 {
+		param = param[i+1:]
+	}
+	key, ok := strings.CutSuffix(param, "=")
+	if !ok || key == "" || strings.ContainsAny(key, "{}=") {
+		return ""
+	}
+	return key
+}
+
+// queryStart returns the position of the `?` that starts the query string,
+// ignoring the ones inside of placeholders.
+func queryStart(source string) int {
+	depth := 0
+	for i := 0; i < len(source); i++ {
+		switch source[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '?':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Expand substitutes args into the template. Arguments in the `name=value`
+// form are bound to the placeholder with that name, the rest are positional.
+func (t *URLTemplate) Expand(args []string) string {
+	return t.expand(t.bind(args), false)
+}
+
+// Keyword returns the URL in the format of browser keyword bookmarks, the
+// placeholder which would receive a single argument becomes `%s` and the
+// rest get their defaults.
+func (t *URLTemplate) Keyword() string {
+	return t.expand(t.bind([]string{"%s"}), true)
+}
+
+// expand writes the values into the template, raw values are not encoded.
+// The query string and the fragment are dropped when all placeholders in
+// them are empty, ex. `{q}#{section?}` doesn't leave a dangling `#`.
+func (t *URLTemplate) expand(values map[int]string, raw bool) string {
+	var b strings.Builder
+	dropped := make(map[string]bool)
+	query, fragment := urlPart{start: -1}, urlPart{start: -1}
+	for _, s := range t.segments {
+		if s.placeholder < 0 {
+			if i := strings.IndexByte(s.literal, '?'); i >= 0 && query.start < 0 && fragment.start < 0 {
+				query.start = b.Len() + i
+			}
+			if i := strings.IndexByte(s.literal, '#'); i >= 0 && fragment.start < 0 {
+				fragment.start = b.Len() + i
+			}
+			b.WriteString(s.literal)
+			continue
+		}
+		p := t.Placeholders[s.placeholder]
+		value, ok := values[s.placeholder]
+		if !ok {
+			if p.Optional && p.QueryKey != "" {
+				dropped[p.QueryKey] = true
+			}
+			value = p.Default
+		}
+		if ok && raw {
+			b.WriteString(value)
+		} else {
+			b.WriteString(p.Encode(value))
+		}
+		if fragment.start < 0 {
+			query.add(value)
+		}
+		fragment.add(value)
+	}
+	u := b.String()
+	if fragment.empty() {
+		u = u[:fragment.start]
+	}
+	if query.empty() {
+		if rest := u[query.start+1:]; rest == "" || rest[0] == '#' {
+			u = u[:query.start] + rest
+		}
+	}
+	return dropQueryParams(u, dropped)
+}
+
+// urlPart is the query string or the fragment of an expanded template,
+// which starts at the first `?` or `#` outside of the placeholders.
+type urlPart struct {
+	start        int
+	placeholders int
+	filled       bool
+}
+
+func (p *urlPart) add(value string) {
+	if p.start < 0 {
+		return
+	}
+	p.placeholders++
+	p.filled = p.filled || value != ""
+}
+
+// empty is true when the part has placeholders and all of them are empty.
+func (p urlPart) empty() bool {
+	return p.start >= 0 && p.placeholders > 0 && !p.filled
+}
+
+func (t *URLTemplate) bind(args []string) map[int]string {
+	values := make(map[int]string)
+	positional := make([]string, 0, len(args))
+	for _, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok {
+			if idx := t.placeholderIndex(name); idx >= 0 {
+				values[idx] = value
+				continue
+			}
+		}
+		positional = append(positional, arg)
+	}
+
+	unfilled := make([]int, 0, len(t.Placeholders))
+	for i := range t.Placeholders {
+		if _, ok := values[i]; !ok {
+			unfilled = append(unfilled, i)
+		}
+	}
+	if len(unfilled) == 0 || len(positional) == 0 {
+		return values
+	}
+
+	targets := unfilled
+	if len(positional) < len(unfilled) {
+		// Not enough arguments for everything, required placeholders go
+		// first and the rest is filled in the template order.
+		chosen := make(map[int]bool)
+		for _, i := range unfilled {
+			if len(chosen) < len(positional) && t.Placeholders[i].Required() {
+				chosen[i] = true
+			}
+		}
+		for _, i := range unfilled {
+			if len(chosen) < len(positional) {
+				chosen[i] = true
+			}
+		}
+		targets = make([]int, 0, len(positional))
+		for _, i := range unfilled {
+			if chosen[i] {
+				targets = append(targets, i)
+			}
+		}
+	}
+	for n, i := range targets {
+		if n == len(targets)-1 {
+			values[i] = strings.Join(positional[n:], " ")
+			break
+		}
+		values[i] = positional[n]
+	}
+	return values
+}
+
+func (t *URLTemplate) placeholderIndex(name string) int {
+	for i, p := range t.Placeholders {
+		if p.Name != "" && p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// dropQueryParams removes empty `key=` parameters left by optional
+// placeholders without a value.
+func dropQueryParams(u string, keys map[string]bool) string {
+	if len(keys) == 0 {
+		return u
+	}
+	base, query, ok := strings.Cut(u, "?")
+	if !ok {
+		return u
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+	params := strings.Split(query, "&")
+	kept := params[:0]
+	for _, param := range params {
+		if key, ok := strings.CutSuffix(param, "="); ok && keys[key] {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	result := base
+	if len(kept) > 0 {
+		result += "?" + strings.Join(kept, "&")
+	}
+	if hasFragment {
+		result += "#" + fragment
+	}
+	return result
+}
+
+func pathEscape(s string) string {
+	segments := strings.Split(s, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func rawEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte("\"<>\\^`{|}", c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
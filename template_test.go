@@ -0,0 +1,285 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileTemplate(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		args   []string
+		want   string
+	}{
+		{
+			name:   "legacy query",
+			source: "https://google.com/search?q=%s",
+			args:   []string{"a", "b&c"},
+			want:   "https://google.com/search?q=a+b%26c",
+		},
+		{
+			name:   "legacy path",
+			source: "https://github.com/%s/%s",
+			args:   []string{"biozz", "links"},
+			want:   "https://github.com/biozz/links",
+		},
+		{
+			name:   "legacy rest of the args",
+			source: "https://github.com/%s?q=%s",
+			args:   []string{"biozz", "a", "b"},
+			want:   "https://github.com/biozz?q=a+b",
+		},
+		{
+			name:   "legacy with braces",
+			source: `https://grafana/explore?left={"expr":"up{job=\"%s\"}"}`,
+			args:   []string{"api"},
+			want:   `https://grafana/explore?left={"expr":"up{job=\"api\"}"}`,
+		},
+		{
+			name:   "named",
+			source: "https://github.com/search?q={query}",
+			args:   []string{"hello", "world"},
+			want:   "https://github.com/search?q=hello+world",
+		},
+		{
+			name:   "default",
+			source: "https://jira/browse/{project=CORE}-{id}",
+			args:   []string{"12"},
+			want:   "https://jira/browse/CORE-12",
+		},
+		{
+			name:   "default overridden",
+			source: "https://jira/browse/{project=CORE}-{id}",
+			args:   []string{"WEB", "12"},
+			want:   "https://jira/browse/WEB-12",
+		},
+		{
+			name:   "bind by name",
+			source: "https://jira/browse/{project=CORE}-{id}",
+			args:   []string{"project=WEB", "12"},
+			want:   "https://jira/browse/WEB-12",
+		},
+		{
+			name:   "unknown name is positional",
+			source: "https://google.com/search?q={q}",
+			args:   []string{"a=b"},
+			want:   "https://google.com/search?q=a%3Db",
+		},
+		{
+			name:   "optional dropped",
+			source: "https://github.com/issues?q={q?}&page=1",
+			args:   []string{},
+			want:   "https://github.com/issues?page=1",
+		},
+		{
+			name:   "optional filled",
+			source: "https://github.com/issues?q={q?}&page=1",
+			args:   []string{"bug"},
+			want:   "https://github.com/issues?q=bug&page=1",
+		},
+		{
+			name:   "optional fragment dropped",
+			source: "https://x/{q}#{section?}",
+			args:   []string{"a"},
+			want:   "https://x/a",
+		},
+		{
+			name:   "optional fragment filled",
+			source: "https://x/{q}#{section?}",
+			args:   []string{"a", "usage"},
+			want:   "https://x/a#usage",
+		},
+		{
+			name:   "static fragment kept",
+			source: "https://x/{q}#readme",
+			args:   []string{"a"},
+			want:   "https://x/a#readme",
+		},
+		{
+			name:   "optional query without a key dropped",
+			source: "https://x/{q}?{filter?}#{section?}",
+			args:   []string{"a"},
+			want:   "https://x/a",
+		},
+		{
+			name:   "query kept before an empty fragment",
+			source: "https://x/?q={q}#{section?}",
+			args:   []string{"a"},
+			want:   "https://x/?q=a",
+		},
+		{
+			name:   "repeated placeholder",
+			source: "https://x/{q}?again={q}",
+			args:   []string{"a"},
+			want:   "https://x/a?again=a",
+		},
+		{
+			name:   "path",
+			source: "https://github.com/{repo:path}",
+			args:   []string{"biozz/links ng"},
+			want:   "https://github.com/biozz/links%20ng",
+		},
+		{
+			name:   "raw",
+			source: "https://x/{q:raw}",
+			args:   []string{"a/b?c <d>"},
+			want:   "https://x/a/b?c%20%3Cd%3E",
+		},
+		{
+			name:   "base64",
+			source: "https://x/{q:base64}",
+			args:   []string{"hi?"},
+			want:   "https://x/aGk_",
+		},
+		{
+			name:   "slug",
+			source: "https://x/{q:slug}",
+			args:   []string{"Hello,", "World!"},
+			want:   "https://x/hello-world",
+		},
+		{
+			name:   "unknown mode falls back to legacy",
+			source: "https://x/{q:nope}",
+			args:   []string{"a"},
+			want:   "https://x/{q:nope}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compileTemplate(tt.source).Expand(tt.args); got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, source := range []string{
+		"https://x/{q",
+		"https://x/q}",
+		"https://x/{q:nope}",
+		"https://x/{1q}",
+		"https://x/{}",
+		"https://x/{p=1?}",
+	} {
+		if _, err := parseTemplate(source); err == nil {
+			t.Errorf("parseTemplate(%q) succeeded", source)
+		}
+	}
+}
+
+func TestTemplateKeyword(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{source: "https://google.com/search?q=%s", want: "https://google.com/search?q=%s"},
+		{source: "https://jira/browse/{project=CORE}-{id}", want: "https://jira/browse/CORE-%s"},
+		{source: "https://github.com/issues?q={q?}", want: "https://github.com/issues?q=%s"},
+	}
+	for _, tt := range tests {
+		if got := compileTemplate(tt.source).Keyword(); got != tt.want {
+			t.Errorf("Keyword(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestVariantFor(t *testing.T) {
+	item := Item{
+		URL:      "https://x/{q}",
+		Variants: Variants{0: "https://x", 1: "https://x/one/{a}", 3: "https://x/three/{a}/{b}/{c}"},
+	}
+	tests := []struct {
+		arity   int
+		url     string
+		variant string
+	}{
+		{arity: 0, url: "https://x", variant: "0"},
+		{arity: 1, url: "https://x/one/{a}", variant: "1"},
+		{arity: 2, url: "https://x/one/{a}", variant: "1"},
+		{arity: 3, url: "https://x/three/{a}/{b}/{c}", variant: "3"},
+		{arity: 5, url: "https://x/three/{a}/{b}/{c}", variant: "3"},
+	}
+	for _, tt := range tests {
+		url, variant := item.variantFor(tt.arity)
+		if url != tt.url || variant != tt.variant {
+			t.Errorf("variantFor(%d) = %q, %q, want %q, %q", tt.arity, url, variant, tt.url, tt.variant)
+		}
+	}
+	homepage := Item{URL: "https://x/{q}", Variants: Variants{0: "https://x"}}
+	if url, variant := homepage.variantFor(1); url != homepage.URL || variant != "" {
+		t.Errorf("variantFor(1) = %q, %q, want the item URL", url, variant)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	item := Item{
+		Alias:    "gh",
+		URL:      "https://github.com/search?q={q}",
+		Variants: Variants{0: "https://github.com", 2: "https://github.com/{owner}/{repo}"},
+		Path:     "gh",
+	}
+	tests := []struct {
+		args    []string
+		url     string
+		variant string
+	}{
+		{args: []string{}, url: "https://github.com", variant: "0"},
+		{args: []string{"links"}, url: "https://github.com/search?q=links", variant: ""},
+		{args: []string{"biozz", "links"}, url: "https://github.com/biozz/links", variant: "2"},
+		{args: []string{"biozz", "links", "ng"}, url: "https://github.com/biozz/links+ng", variant: "2"},
+	}
+	for _, tt := range tests {
+		expansion := expand(item, tt.args)
+		if expansion.URL != tt.url || expansion.Variant != tt.variant {
+			t.Errorf("expand(%q) = %q (variant %q), want %q (variant %q)", tt.args, expansion.URL, expansion.Variant, tt.url, tt.variant)
+		}
+		if expansion.Alias != "gh" {
+			t.Errorf("expand(%q).Alias = %q, want gh", tt.args, expansion.Alias)
+		}
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		source string
+		valid  bool
+	}{
+		{source: "", valid: true},
+		{source: "https://github.com/search?q={query}", valid: true},
+		{source: "https://google.com/search?q=%s", valid: true},
+		{source: `https://grafana/explore?left={"queries":[{"expr":"up{job=\"%s\"}"}]}`, valid: true},
+		{source: "https://github.com/search?q={query", valid: false},
+		{source: `https://grafana/explore?left={"expr":"up"`, valid: false},
+		{source: "javascript:alert(%s)", valid: false},
+		{source: " JavaScript:alert(1)", valid: false},
+	}
+	for _, tt := range tests {
+		err := validateTemplate(tt.source)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("validateTemplate(%q) = %v, want valid %v", tt.source, err, tt.valid)
+		}
+	}
+}
+
+func TestParseVariants(t *testing.T) {
+	variants := Variants{0: "https://github.com", 2: "https://github.com/{owner}/{repo}"}
+	text := formatVariants(variants)
+	if text != "0 https://github.com\n2 https://github.com/{owner}/{repo}" {
+		t.Errorf("formatVariants = %q", text)
+	}
+	parsed, err := parseVariants("\r\n  " + strings.ReplaceAll(text, "\n", "\r\n") + "\r\n")
+	if err != nil || !reflect.DeepEqual(parsed, variants) {
+		t.Errorf("parseVariants = %v, %v, want %v", parsed, err, variants)
+	}
+	if parsed, err := parseVariants(" \n"); err != nil || parsed != nil {
+		t.Errorf("parseVariants of a blank text = %v, %v", parsed, err)
+	}
+	for _, text := range []string{"https://github.com", "x https://github.com", "1"} {
+		if _, err := parseVariants(text); err == nil {
+			t.Errorf("parseVariants(%q) succeeded", text)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !goexperiment.jsonv2
+
+package main
+
+// JSON_V2 is false with encoding/json v1, which PocketBase v0.22 is built
+// for.
+const JSON_V2 = false
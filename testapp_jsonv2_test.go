@@ -0,0 +1,7 @@
+//go:build goexperiment.jsonv2
+
+package main
+
+// JSON_V2 skips the tests which need a PocketBase app, its collections
+// can't be decoded with encoding/json v2.
+const JSON_V2 = true
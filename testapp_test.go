@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/biozz/links/web"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/migrate"
+)
+
+// newTestApp creates an app with a migrated database in a temporary
+// directory and the item form routes.
+func newTestApp(t *testing.T) (*pocketbase.PocketBase, *echo.Echo) {
+	t.Helper()
+	if JSON_V2 {
+		t.Skip("PocketBase v0.22 can't decode its collections with encoding/json v2, run with GOEXPERIMENT=nojsonv2")
+	}
+	pb := pocketbase.NewWithConfig(pocketbase.Config{DefaultDataDir: t.TempDir()})
+	if err := pb.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pb.ResetBootstrapState() })
+	runner, err := migrate.NewRunner(pb.DB(), migrations.AppMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.Up(); err != nil {
+		t.Fatal(err)
+	}
+	registerItemHooks(pb)
+	registerDeviceHooks(pb)
+	router := echo.New()
+	registerItemForms(router, pb, web.NewTemplates(false), &AuthMiddleware{pb})
+	return pb, router
+}
+
+// newTestDevice returns the token of a new device of the user.
+func newTestDevice(t *testing.T, pb *pocketbase.PocketBase, name string, user string) string {
+	t.Helper()
+	_, token, err := createDevice(pb.Dao(), name, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// newTestUser returns the ID of a new user.
+func newTestUser(t *testing.T, pb *pocketbase.PocketBase, username string) string {
+	t.Helper()
+	users, err := pb.Dao().FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.NewRecord(users)
+	user.SetUsername(username)
+	user.SetEmail(username + "@example.com")
+	user.RefreshTokenKey()
+	if err := user.SetPassword("1234567890"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pb.Dao().SaveRecord(user); err != nil {
+		t.Fatal(err)
+	}
+	return user.Id
+}
+
+// submitJSON sends the body as JSON instead of a form.
+func submitJSON(router *echo.Echo, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// submitForm sends the form the same way htmx does.
+func submitForm(router *echo.Echo, method string, path string, token string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+)
+
+// Correction describes an alias, which was replaced with an existing one.
+type Correction struct {
+	From   string
+	To     string
+	Reason string
+	// Expansion of the query with the corrected alias
+	Expansion Expansion
+}
+
+func (c Correction) String() string {
+	return fmt.Sprintf("%s -> %s (%s)", c.From, c.To, c.Reason)
+}
+
+// TYPO_FOLLOW_MIN_LENGTH is the shortest alias, which correction can be
+// followed without asking.
+const TYPO_FOLLOW_MIN_LENGTH = 5
+
+// typoCutoff is the maximum edit distance at which an alias is still
+// considered a typo, short aliases allow less edits.
+func typoCutoff(alias string) int {
+	switch n := utf8.RuneCountInString(alias); {
+	case n <= 1:
+		return 0
+	case n <= 4:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the Damerau–Levenshtein distance in its optimal string
+// alignment form: insertions, deletions, substitutions and transpositions of
+// adjacent characters cost one edit each.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
+
+// followsTypo tells whether a confident correction of the query alias can be
+// followed. Short aliases are often ordinary words and queries with
+// arguments look like searches, so those go to the fallback engine and the
+// correction is only suggested.
+func followsTypo(query Query) bool {
+	return utf8.RuneCountInString(query.Alias()) >= TYPO_FOLLOW_MIN_LENGTH && len(query.Args()) == 0
+}
+
+// getSimilarItems returns top level items with aliases within the typo
+// cutoff, the closest ones go first. The closest item is confident when it
+// is a single edit away and there are no other items as close as it is.
+func getSimilarItems(pb *pocketbase.PocketBase, alias string, user string) ([]Item, bool) {
+	cutoff := typoCutoff(alias)
+	if cutoff == 0 {
+		return []Item{}, false
+	}
+	candidates := make([]Item, 0)
+	pb.Dao().DB().
+		Select("id", "parent", "owner", "alias", "name", "url", "variants", "tags").
+		From("items").
+		Where(dbx.HashExp{"parent": ""}).
+		AndWhere(visibleItemsExp(user)).
+		All(&candidates)
+	distances := make(map[string]int)
+	similar := make([]Item, 0)
+	for _, item := range candidates {
+		// Aliases of very different lengths can't be within the cutoff
+		diff := utf8.RuneCountInString(item.Alias) - utf8.RuneCountInString(alias)
+		if diff > cutoff || -diff > cutoff {
+			continue
+		}
+		if d := editDistance(alias, item.Alias); d <= cutoff {
+			distances[item.ID] = d
+			similar = append(similar, item)
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool {
+		di, dj := distances[similar[i].ID], distances[similar[j].ID]
+		if di != dj {
+			return di < dj
+		}
+		return similar[i].Alias < similar[j].Alias
+	})
+	if len(similar) > SEARCH_LIMIT {
+		similar = similar[:SEARCH_LIMIT]
+	}
+	similar = withPaths(similar, Item{})
+	confident := len(similar) > 0 && distances[similar[0].ID] == 1 &&
+		(len(similar) == 1 || distances[similar[1].ID] > 1)
+	return similar, confident
+}
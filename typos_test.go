@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "gh", b: "gh", want: 0},
+		{a: "", b: "gh", want: 2},
+		{a: "gh", b: "hg", want: 1},
+		{a: "gh", b: "g", want: 1},
+		{a: "gh", b: "gl", want: 1},
+		{a: "jira", b: "jria", want: 1},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "ca", b: "abc", want: 3},
+		{a: "пр", b: "рп", want: 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestTypoCutoff(t *testing.T) {
+	tests := map[string]int{"g": 0, "gh": 1, "jira": 1, "links": 2, "пр": 1}
+	for alias, want := range tests {
+		if got := typoCutoff(alias); got != want {
+			t.Errorf("typoCutoff(%q) = %d, want %d", alias, got, want)
+		}
+	}
+}
+
+func TestFollowsTypo(t *testing.T) {
+	tests := []struct {
+		q    string
+		want bool
+	}{
+		{q: "lniks", want: true},
+		{q: "lniks ", want: true},
+		{q: "!lniks", want: true},
+		{q: "go", want: false},
+		{q: "go generics", want: false},
+		{q: "lniks generics", want: false},
+		{q: "generics !lniks", want: false},
+		{q: "jria", want: false},
+		{q: "пошта", want: true},
+	}
+	for _, tt := range tests {
+		if got := followsTypo(parseQuery(tt.q)); got != tt.want {
+			t.Errorf("followsTypo(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
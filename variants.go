@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variants are alternative URL templates of an item keyed by the number of
+// arguments they are used for, ex. a homepage for zero arguments and
+// a deep link for two. The item URL is used when no variant matches.
+type Variants map[int]string
+
+func (v *Variants) Scan(value any) error {
+	var data []byte
+	switch value := value.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("failed to scan variants from %T", value)
+	}
+	if len(data) == 0 || string(data) == "null" {
+		*v = nil
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (v Variants) Value() (driver.Value, error) {
+	if v == nil {
+		return "null", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// UnmarshalYAML accepts both plain and quoted arity keys, ex. `0:` and
+// `"0":`, the latter is what JSON manifests have.
+func (v *Variants) UnmarshalYAML(node *yaml.Node) error {
+	var raw map[string]string
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*v = nil
+		return nil
+	}
+	variants := make(Variants, len(raw))
+	for key, url := range raw {
+		arity, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("line %d: variant key %q is not a number of arguments", node.Line, key)
+		}
+		variants[arity] = url
+	}
+	*v = variants
+	return nil
+}
+
+// formatVariants writes the variants one per line as `<arity> <url>`,
+// ordered by the arity, which is how they are edited in the item form.
+func formatVariants(v Variants) string {
+	arities := make([]int, 0, len(v))
+	for arity := range v {
+		arities = append(arities, arity)
+	}
+	sort.Ints(arities)
+	lines := make([]string, 0, len(arities))
+	for _, arity := range arities {
+		lines = append(lines, fmt.Sprintf("%d %s", arity, v[arity]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseVariants reads the variants written by formatVariants, blank lines
+// are skipped.
+func parseVariants(s string) (Variants, error) {
+	var variants Variants
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, url, _ := strings.Cut(line, " ")
+		arity, err := strconv.Atoi(key)
+		if err != nil || strings.TrimSpace(url) == "" {
+			return nil, fmt.Errorf("line %d: expected the number of arguments and a URL", i+1)
+		}
+		if variants == nil {
+			variants = make(Variants)
+		}
+		variants[arity] = strings.TrimSpace(url)
+	}
+	return variants, nil
+}
+
+// variantFor returns the URL template for the given number of arguments and
+// the key of the selected variant, which is empty for the item URL. Without
+// an exact match the variant for the most arguments below the given number
+// is used, its last placeholder receives the rest of them. The variant for
+// zero arguments has nowhere to put them, so it is only used without any.
+func (i Item) variantFor(arity int) (string, string) {
+	if url, ok := i.Variants[arity]; ok {
+		return url, strconv.Itoa(arity)
+	}
+	best := 0
+	for n := range i.Variants {
+		if n > best && n < arity {
+			best = n
+		}
+	}
+	if best > 0 {
+		return i.Variants[best], strconv.Itoa(best)
+	}
+	return i.URL, ""
+}
@@ -41,6 +41,10 @@ func (t *Templates) Must() {
 		"stats":      template.Must(template.New("").ParseFS(t.fsys, "stats.html.tmpl")),
 		"new":        template.Must(template.New("").ParseFS(t.fsys, "new.html.tmpl", "layout.html.tmpl")),
 		"login":      template.Must(template.New("").ParseFS(t.fsys, "login.html.tmpl", "layout.html.tmpl")),
+		"import":     template.Must(template.New("").ParseFS(t.fsys, "import.html.tmpl", "layout.html.tmpl")),
+		"imported":   template.Must(template.New("").ParseFS(t.fsys, "import.html.tmpl")),
+		"devices":    template.Must(template.New("").ParseFS(t.fsys, "devices.html.tmpl", "layout.html.tmpl")),
+		"pairing":    template.Must(template.New("").ParseFS(t.fsys, "devices.html.tmpl")),
 		"opensearch": template.Must(template.New("").ParseFS(t.fsys, "opensearch.xml.tmpl")),
 	}
 }